@@ -0,0 +1,22 @@
+package filesystem
+
+import (
+	"github.com/galaco/bsp"
+)
+
+// RegisterBsp opens the bsp at path and mounts its pakfile with priority,
+// using path as its name. Content packed into the map is then found before
+// anything else, as it would be in game.
+func (fs *Filesystem) RegisterBsp(path string, priority int) error {
+	file, err := bsp.ReadFromFile(path)
+	if err != nil {
+		return err
+	}
+
+	return fs.RegisterPakFileData(path, file.RawLump(bsp.LumpPakfile).RawContents(), priority)
+}
+
+// UnregisterBsp removes the pakfile of the bsp at path
+func (fs *Filesystem) UnregisterBsp(path string) {
+	fs.UnregisterPakFile(path)
+}
@@ -0,0 +1,172 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/galaco/bsp"
+)
+
+// pakData zips files into the data of a pakfile
+func pakData(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+
+	for name, contents := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := f.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// writeBsp writes a bsp with nothing in it but pak as its pakfile
+func writeBsp(t *testing.T, filename string, pak []byte) {
+	t.Helper()
+
+	header := bsp.Header{
+		Id:      0x50534256, // VBSP
+		Version: 20,
+	}
+
+	// The pakfile goes straight after the header
+	header.Lumps[bsp.LumpPakfile] = bsp.HeaderLump{
+		Offset: int32(binary.Size(header)),
+		Length: int32(len(pak)),
+	}
+
+	buf := &bytes.Buffer{}
+	if err := binary.Write(buf, binary.LittleEndian, &header); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Write(pak)
+
+	writeFile(t, filename, buf.String())
+}
+
+func readString(t *testing.T, fs *Filesystem, filename string) string {
+	t.Helper()
+
+	r, err := fs.GetFile(filename)
+	if err != nil {
+		t.Fatalf("%s: %v", filename, err)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestRegisterBsp(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "game", "materials", "a.vmt"), "disk")
+
+	low := filepath.Join(dir, "maps", "low.bsp")
+	writeBsp(t, low, pakData(t, map[string]string{
+		"materials/a.vmt":       "low",
+		"materials/b.vmt":       "low",
+		"materials/maps/c.vtf":  "low",
+		"materials/Upper/D.VMT": "low",
+	}))
+
+	high := filepath.Join(dir, "maps", "high.bsp")
+	writeBsp(t, high, pakData(t, map[string]string{
+		"materials/a.vmt": "high",
+	}))
+
+	fs := NewFilesystem()
+	fs.RegisterLocalDirectory(filepath.Join(dir, "game"))
+
+	if err := fs.RegisterBsp(low, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.RegisterBsp(high, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Also packed but it comes after every priority so is never used
+	if err := fs.RegisterPakFileData("lowest", pakData(t, map[string]string{"materials/a.vmt": "lowest"}), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readString(t, fs, "materials/a.vmt"); got != "high" {
+		t.Errorf("materials/a.vmt came from %s, want high", got)
+	}
+
+	if got := readString(t, fs, "materials/b.vmt"); got != "low" {
+		t.Errorf("materials/b.vmt came from %s, want low", got)
+	}
+
+	if got := fs.List("materials", false); !reflect.DeepEqual(got, []string{"materials/a.vmt", "materials/b.vmt"}) {
+		t.Errorf("List found %v", got)
+	}
+
+	if got := fs.List("materials", true); !reflect.DeepEqual(got, []string{"materials/a.vmt", "materials/b.vmt", "materials/maps/c.vtf", "materials/upper/d.vmt"}) {
+		t.Errorf("recursive List found %v", got)
+	}
+
+	if got, err := fs.Glob("materials/*/*"); err != nil || !reflect.DeepEqual(got, []string{"materials/maps/c.vtf", "materials/upper/d.vmt"}) {
+		t.Errorf("Glob found %v %v", got, err)
+	}
+
+	// Unregistering one map leaves the others alone
+	fs.UnregisterBsp(low)
+
+	if fs.Exists("materials/b.vmt") || fs.Exists("materials/maps/c.vtf") {
+		t.Error("files from the unregistered map are still there")
+	}
+
+	if got := readString(t, fs, "materials/a.vmt"); got != "high" {
+		t.Errorf("materials/a.vmt came from %s, want high", got)
+	}
+
+	if got, _ := fs.Glob("materials/*/*"); len(got) != 0 {
+		t.Errorf("Glob still found %v", got)
+	}
+
+	fs.UnregisterBsp(high)
+	fs.UnregisterPakFile("lowest")
+
+	if got := readString(t, fs, "materials/a.vmt"); got != "disk" {
+		t.Errorf("materials/a.vmt came from %s, want disk", got)
+	}
+
+	if len(fs.Mounts()) != 1 {
+		t.Errorf("%d mounts are left, want the directory", len(fs.Mounts()))
+	}
+}
+
+func TestRegisterBspMissing(t *testing.T) {
+	fs := NewFilesystem()
+
+	if err := fs.RegisterBsp(filepath.Join(t.TempDir(), "missing.bsp"), 1); err == nil {
+		t.Error("registered a bsp that does not exist")
+	}
+
+	if len(fs.Mounts()) != 0 {
+		t.Errorf("%d mounts were added", len(fs.Mounts()))
+	}
+}
@@ -1,10 +1,11 @@
 package filesystem
 
 import (
+	"archive/zip"
 	"bytes"
 	"io"
-	"io/ioutil"
-	"os"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/galaco/bsp/lumps"
@@ -12,141 +13,309 @@ import (
 )
 
 type Filesystem struct {
-	gameVPKs         map[string]vpk.VPK
-	localDirectories []string
-	pakFile          *lumps.Pakfile
+	// Every location in search order
+	mounts []*Mount
+
+	// Built on first use by the io/fs functions
+	dirs dirIndex
+
+	// Told about every file that is read
+	watcher *Watcher
 }
 
 func NewFilesystem() *Filesystem {
 	return &Filesystem{
-		gameVPKs:         map[string]vpk.VPK{},
-		localDirectories: []string{},
-		pakFile:          nil,
+		mounts: []*Mount{},
 	}
 }
 
-// PakFile returns loaded pakfile
-// There can only be 1 registered pakfile at once.
-func (fs *Filesystem) PakFile() *lumps.Pakfile {
-	return fs.pakFile
+// Mounts returns every mount in the order they are searched
+func (fs *Filesystem) Mounts() []*Mount {
+	return fs.mounts
 }
 
-// RegisterVpk registers a vpk package as a valid
-// asset directory
-func (fs *Filesystem) RegisterVpk(path string, vpkFile *vpk.VPK) {
-	fs.gameVPKs[path] = *vpkFile
+// PakFile returns the pakfile registered with RegisterPakFile
+// as name, or nil if there is not one
+func (fs *Filesystem) PakFile(name string) *lumps.Pakfile {
+	for _, m := range fs.mounts {
+		if m.kind == mountPakfile && m.Path == name {
+			return m.pakFile
+		}
+	}
+
+	return nil
 }
 
-func (fs *Filesystem) UnregisterVpk(path string) {
-	for key := range fs.gameVPKs {
-		if key == path {
-			delete(fs.gameVPKs, key)
+func (fs *Filesystem) addMount(m *Mount) {
+	fs.mounts = append(fs.mounts, m)
+	fs.invalidateIndex()
+}
+
+// insertMount adds m so that it is searched straight after after
+func (fs *Filesystem) insertMount(after *Mount, m *Mount) {
+	for i, other := range fs.mounts {
+		if other == after {
+			fs.mounts = append(fs.mounts[:i+1], append([]*Mount{m}, fs.mounts[i+1:]...)...)
+			fs.invalidateIndex()
+			return
+		}
+	}
+
+	fs.addMount(m)
+}
+
+func (fs *Filesystem) findMount(kind mountKind, path string) *Mount {
+	for _, m := range fs.mounts {
+		if m.kind == kind && m.Path == path {
+			return m
+		}
+	}
+
+	return nil
+}
+
+func (fs *Filesystem) removeMounts(match func(m *Mount) bool) {
+	mounts := fs.mounts[:0]
+
+	for _, m := range fs.mounts {
+		if !match(m) {
+			mounts = append(mounts, m)
 		}
 	}
+
+	fs.mounts = mounts
+	fs.invalidateIndex()
+}
+
+// RegisterVpk registers a vpk package as a valid
+// asset directory. It is searched after everything
+// that is already registered.
+func (fs *Filesystem) RegisterVpk(path string, vpkFile *vpk.VPK, pathIDs ...string) {
+	m := newMount(mountVpk, path, pathIDs)
+	m.vpk = vpkFile
+
+	fs.addMount(m)
+}
+
+func (fs *Filesystem) UnregisterVpk(path string) {
+	fs.removeMounts(func(m *Mount) bool {
+		return m.kind == mountVpk && m.Path == path
+	})
 }
 
 // RegisterLocalDirectory register a filesystem path as a valid
-// asset directory
-func (fs *Filesystem) RegisterLocalDirectory(directory string) {
-	fs.localDirectories = append(fs.localDirectories, directory)
+// asset directory. It is searched after everything that is
+// already registered.
+func (fs *Filesystem) RegisterLocalDirectory(directory string, pathIDs ...string) {
+	fs.addMount(newMount(mountDirectory, directory, pathIDs))
 }
 
 func (fs *Filesystem) UnregisterLocalDirectory(directory string) {
-	for idx, dir := range fs.localDirectories {
-		if dir == directory {
-			if len(fs.localDirectories) == 1 {
-				fs.localDirectories = make([]string, 0)
-				return
+	fs.removeMounts(func(m *Mount) bool {
+		return m.kind == mountDirectory && m.Path == directory
+	})
+}
+
+// RegisterPakFile sets a pakfile to be used as an asset directory called name,
+// replacing any pakfile already called that.
+// Pakfiles are searched before anything else, highest priority first.
+func (fs *Filesystem) RegisterPakFile(name string, pakFile *lumps.Pakfile, priority int) {
+	m := newMount(mountPakfile, name, []string{PathIDBsp})
+	m.Priority = priority
+	m.pakFile = pakFile
+
+	fs.addPakFile(m)
+}
+
+// RegisterPakFileData mounts the zip data of a pakfile in the same way as
+// RegisterPakFile. Unlike RegisterPakFile its contents can be listed.
+func (fs *Filesystem) RegisterPakFileData(name string, data []byte, priority int) error {
+	m := newMount(mountPakfile, name, []string{PathIDBsp})
+	m.Priority = priority
+	m.zipFiles = map[string]*zip.File{}
+
+	// Maps with nothing packed have an empty pakfile
+	if len(data) != 0 {
+		r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+
+		for _, f := range r.File {
+			if !f.FileInfo().IsDir() {
+				m.zipFiles[strings.ToLower(NormalisePath(f.Name))] = f
 			}
-			fs.localDirectories = append(fs.localDirectories[:idx], fs.localDirectories[idx+1:]...)
 		}
 	}
+
+	fs.addPakFile(m)
+
+	return nil
+}
+
+// addPakFile adds the pakfile m after the other pakfiles with
+// at least its priority and before everything else
+func (fs *Filesystem) addPakFile(m *Mount) {
+	fs.UnregisterPakFile(m.Path)
+
+	i := 0
+	for i < len(fs.mounts) && fs.mounts[i].kind == mountPakfile && fs.mounts[i].Priority >= m.Priority {
+		i++
+	}
+
+	fs.mounts = append(fs.mounts[:i], append([]*Mount{m}, fs.mounts[i:]...)...)
+	fs.invalidateIndex()
 }
 
-// RegisterPakFile Set a pakfile to be used as an asset directory.
-// This would normally be called during each map load
-func (fs *Filesystem) RegisterPakFile(pakFile *lumps.Pakfile) {
-	fs.pakFile = pakFile
+// UnregisterPakFile removes the pakfile called name from
+// available search locations
+func (fs *Filesystem) UnregisterPakFile(name string) {
+	fs.removeMounts(func(m *Mount) bool {
+		return m.kind == mountPakfile && m.Path == name
+	})
 }
 
-// UnregisterPakFile removes the current pakfile from
+// UnregisterPakFiles removes every pakfile from
 // available search locations
-func (fs *Filesystem) UnregisterPakFile() {
-	fs.pakFile = nil
+func (fs *Filesystem) UnregisterPakFiles() {
+	fs.removeMounts(func(m *Mount) bool {
+		return m.kind == mountPakfile
+	})
 }
 
-// EnumerateResourcePaths returns all registered resource paths.
-// PakFile is excluded.
+// EnumerateResourcePaths returns all registered resource paths
+// in search order. Pakfiles are excluded.
 func (fs *Filesystem) EnumerateResourcePaths() []string {
 	list := make([]string, 0)
 
-	for idx := range fs.gameVPKs {
-		list = append(list, string(idx))
+	for _, m := range fs.mounts {
+		if m.kind != mountPakfile {
+			list = append(list, m.Path)
+		}
 	}
 
-	list = append(list, fs.localDirectories...)
-
 	return list
 }
 
-// GetFile attempts to get stream for filename.
-// Search order is Pak->FileSystem->VPK
-func (fs *Filesystem) GetFile(filename string) (io.Reader, error) {
-	// sanitise file
-	searchPath := NormalisePath(strings.ToLower(filename))
-
-	// try to read from pakfile first
-	if fs.pakFile != nil {
-		f, err := fs.pakFile.GetFile(searchPath)
-		if err == nil && f != nil && len(f) != 0 {
-			return bytes.NewReader(f), nil
+// Exists returns whether filename is in any mount
+func (fs *Filesystem) Exists(filename string) bool {
+	searchPath := searchPathOf(filename)
+
+	for _, m := range fs.mounts {
+		if m.has(searchPath) {
+			return true
 		}
 	}
 
-	// Fallback to local filesystem
-	for _, dir := range fs.localDirectories {
-		if _, err := os.Stat(dir + "\\" + searchPath); os.IsNotExist(err) {
-			continue
-		}
-		file, err := ioutil.ReadFile(dir + searchPath)
-		if err != nil {
-			return nil, err
-		}
-		return bytes.NewBuffer(file), nil
+	return false
+}
+
+// List returns the lower case paths of the files in dir (such as
+// materials/dev/) across every mount. If recursive is set files in
+// directories under dir are included too.
+func (fs *Filesystem) List(dir string, recursive bool) []string {
+	dir = searchPathOf(dir)
+	if dir == "." {
+		dir = ""
+	}
+
+	prefix := dir
+	if prefix != "" {
+		prefix += "/"
 	}
 
-	// Fall back to game vpk
-	for _, vfs := range fs.gameVPKs {
-		entry := vfs.Entry(searchPath)
-		if entry != nil {
-			return entry.Open()
+	seen := map[string]bool{}
+	results := []string{}
+
+	for _, m := range fs.mounts {
+		paths := m.Paths()
+
+		// Paths are sorted so everything under prefix is together
+		start := sort.SearchStrings(paths, prefix)
+
+		for _, p := range paths[start:] {
+			if !strings.HasPrefix(p, prefix) {
+				break
+			}
+
+			if !recursive && strings.Contains(p[len(prefix):], "/") {
+				continue
+			}
+
+			if !seen[p] {
+				seen[p] = true
+				results = append(results, p)
+			}
 		}
 	}
 
-	return nil, NewFileNotFoundError(filename)
+	sort.Strings(results)
+
+	return results
 }
 
-// AllPaths returns all the paths (files) that are currently loaded
-func (fs *Filesystem) AllPaths() []string {
-	results := []string{}
+// GetFile attempts to get stream for filename.
+// Mounts are searched in the order they were registered, except
+// for the pakfile which is always searched first.
+func (fs *Filesystem) GetFile(filename string) (io.Reader, error) {
+	r, _, err := fs.getFile(filename, "")
+	return r, err
+}
+
+// GetFileFromPathID is GetFile but only searches mounts with pathID
+func (fs *Filesystem) GetFileFromPathID(filename string, pathID string) (io.Reader, error) {
+	r, _, err := fs.getFile(filename, pathID)
+	return r, err
+}
+
+// Resolve returns the mount that GetFile would read filename from.
+// This is useful for finding out what is overriding a file.
+func (fs *Filesystem) Resolve(filename string) (*Mount, error) {
+	_, m, err := fs.getFile(filename, "")
+	return m, err
+}
+
+func (fs *Filesystem) getFile(filename string, pathID string) (io.Reader, *Mount, error) {
+	searchPath := searchPathOf(filename)
+
+	for _, m := range fs.mounts {
+		if pathID != "" && !m.HasPathID(pathID) {
+			continue
+		}
 
-	// Start with the local directories
-	for _, dir := range fs.localDirectories {
-		finfo, err := ioutil.ReadDir(dir)
-		if err == nil {
-			for _, f := range finfo {
-				// TODO check this returns the correct file name
-				results = append(results, f.Name())
+		r, err := m.open(searchPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if r != nil {
+			if fs.watcher != nil {
+				fs.watcher.add(searchPath)
 			}
+
+			return r, m, nil
 		}
 	}
 
-	// Now do vpks
-	for _, vfs := range fs.gameVPKs {
-		results = append(results, vfs.Paths()...)
+	return nil, nil, NewFileNotFoundError(filename)
+}
+
+// Refresh throws away the cached contents of every mount
+// so that files added or removed on disk are found
+func (fs *Filesystem) Refresh() {
+	for _, m := range fs.mounts {
+		m.refresh()
 	}
 
-	// TODO: handle pak files
+	fs.invalidateIndex()
+}
 
-	return results
+// AllPaths returns all the paths (files) that are currently loaded
+func (fs *Filesystem) AllPaths() []string {
+	return fs.List("", true)
+}
+
+// searchPathOf sanitises filename into the form that mounts index files by
+func searchPathOf(filename string) string {
+	return strings.TrimPrefix(path.Clean(NormalisePath(strings.ToLower(filename))), "/")
 }
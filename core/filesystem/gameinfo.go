@@ -0,0 +1,117 @@
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// GameInfoOptions change how gameinfo.txt is read
+type GameInfoOptions struct {
+	// Platform decides conditionals such as [$LINUX]. It is named the
+	// same way as runtime.GOOS, which is used if it is empty.
+	Platform string
+
+	// ResolveAppId returns the directory app id is installed in.
+	// It is needed for paths starting with |appid_NNN|.
+	ResolveAppId func(appId int) (string, error)
+}
+
+func (opts *GameInfoOptions) platform() string {
+	if opts == nil || opts.Platform == "" {
+		return runtime.GOOS
+	}
+
+	return opts.Platform
+}
+
+// MountError is a search path from gameinfo.txt that could not be mounted
+type MountError struct {
+	// Path ids as they were written, such as game+mod
+	PathIDs string
+	// Path as it was written
+	Path string
+
+	Err error
+}
+
+func (err *MountError) Error() string {
+	return fmt.Sprintf("unable to mount %s (%s): %v", err.Path, err.PathIDs, err.Err)
+}
+
+func (err *MountError) Unwrap() error {
+	return err.Err
+}
+
+// MountErrors are all of the search paths that could not be mounted.
+// Everything else is still mounted.
+type MountErrors []*MountError
+
+func (errs MountErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// conditionMet decides a conditional such as [$WIN32||$POSIX] or [!$X360]
+func conditionMet(condition string, platform string) bool {
+	condition = strings.TrimSpace(strings.Trim(strings.TrimSpace(condition), "[]"))
+
+	for _, alternative := range strings.Split(condition, "||") {
+		met := true
+
+		for _, term := range strings.Split(alternative, "&&") {
+			term = strings.TrimSpace(term)
+
+			negate := strings.HasPrefix(term, "!")
+			term = strings.TrimPrefix(term, "!")
+
+			if platformDefined(strings.TrimPrefix(strings.ToUpper(term), "$"), platform) == negate {
+				met = false
+				break
+			}
+		}
+
+		if met {
+			return true
+		}
+	}
+
+	return false
+}
+
+// platformDefined returns whether the conditional define is set on platform
+func platformDefined(define string, platform string) bool {
+	switch define {
+	case "WINDOWS", "WIN32", "WIN64":
+		return platform == "windows"
+	case "LINUX":
+		return platform == "linux"
+	case "OSX":
+		return platform == "darwin"
+	case "POSIX":
+		return platform != "windows"
+	}
+
+	// Consoles and anything else we do not know about
+	return false
+}
+
+// splitConditional splits a trailing conditional such as [$LINUX] off of s
+func splitConditional(s string) (string, string) {
+	s = strings.TrimSpace(s)
+
+	if !strings.HasSuffix(s, "]") {
+		return s, ""
+	}
+
+	start := strings.LastIndex(s, "[")
+	if start == -1 {
+		return s, ""
+	}
+
+	return strings.TrimSpace(s[:start]), s[start:]
+}
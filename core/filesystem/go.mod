@@ -1,6 +1,6 @@
 module github.com/emily33901/forgery/core/filesystem
 
-go 1.14
+go 1.16
 
 require (
 	github.com/galaco/KeyValues v1.4.1
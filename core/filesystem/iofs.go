@@ -0,0 +1,359 @@
+package filesystem
+
+import (
+	"bytes"
+	"io"
+	iofs "io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filesystem is an io/fs.FS over the merged view of every registered
+// location so that it works with fs.WalkDir, fs.Glob, http.FS and so on.
+// Paths are case insensitive and are always listed in lower case.
+// Pakfiles registered with RegisterPakFile can be opened but are not listed.
+var (
+	_ iofs.FS         = (*Filesystem)(nil)
+	_ iofs.ReadDirFS  = (*Filesystem)(nil)
+	_ iofs.StatFS     = (*Filesystem)(nil)
+	_ iofs.GlobFS     = (*Filesystem)(nil)
+	_ iofs.ReadFileFS = (*Filesystem)(nil)
+)
+
+// dirIndex is every directory in the merged view and what is in it
+type dirIndex map[string]map[string]bool
+
+func (index dirIndex) addFile(name string) {
+	dir := path.Dir(name)
+
+	index.addDir(dir)
+	index[dir][path.Base(name)] = false
+}
+
+func (index dirIndex) addDir(dir string) {
+	if _, ok := index[dir]; ok {
+		return
+	}
+
+	index[dir] = map[string]bool{}
+
+	if dir == "." {
+		return
+	}
+
+	parent := path.Dir(dir)
+
+	index.addDir(parent)
+	index[parent][path.Base(dir)] = true
+}
+
+// index returns the directory index, building it if anything has
+// been registered or unregistered since it was last built
+func (fs *Filesystem) index() dirIndex {
+	if fs.dirs != nil {
+		return fs.dirs
+	}
+
+	index := dirIndex{}
+	index.addDir(".")
+
+	for _, m := range fs.mounts {
+		for _, p := range m.Paths() {
+			index.addFile(p)
+		}
+	}
+
+	fs.dirs = index
+
+	return index
+}
+
+// invalidateIndex throws away the directory index so that
+// it is rebuilt with the current locations on next use
+func (fs *Filesystem) invalidateIndex() {
+	fs.dirs = nil
+}
+
+func cleanFsPath(op, name string) (string, error) {
+	// GetFile accepts backslashes but io/fs paths never have them
+	if !iofs.ValidPath(name) || strings.Contains(name, "\\") {
+		return "", &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+	}
+
+	return strings.ToLower(name), nil
+}
+
+// Open opens the file or directory name
+func (fs *Filesystem) Open(name string) (iofs.File, error) {
+	clean, err := cleanFsPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries, ok := fs.index()[clean]; ok {
+		return &dirFile{
+			info:    newDirInfo(clean),
+			entries: fs.dirEntries(clean, entries),
+		}, nil
+	}
+
+	data, err := fs.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memFile{
+		Reader: bytes.NewReader(data),
+		info:   fileInfo{name: path.Base(clean), size: int64(len(data))},
+	}, nil
+}
+
+// ReadFile reads the whole of the file name
+func (fs *Filesystem) ReadFile(name string) ([]byte, error) {
+	clean, err := cleanFsPath("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := fs.GetFile(clean)
+	if err != nil {
+		return nil, &iofs.PathError{Op: "readfile", Path: name, Err: iofs.ErrNotExist}
+	}
+
+	return ioutil.ReadAll(stream)
+}
+
+// ReadDir lists the directory name sorted by file name
+func (fs *Filesystem) ReadDir(name string) ([]iofs.DirEntry, error) {
+	clean, err := cleanFsPath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, ok := fs.index()[clean]
+	if !ok {
+		return nil, &iofs.PathError{Op: "readdir", Path: name, Err: iofs.ErrNotExist}
+	}
+
+	return fs.dirEntries(clean, entries), nil
+}
+
+// Stat returns information about the file or directory name
+func (fs *Filesystem) Stat(name string) (iofs.FileInfo, error) {
+	clean, err := cleanFsPath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := fs.index()[clean]; ok {
+		return newDirInfo(clean), nil
+	}
+
+	data, err := fs.ReadFile(name)
+	if err != nil {
+		return nil, &iofs.PathError{Op: "stat", Path: name, Err: iofs.ErrNotExist}
+	}
+
+	return fileInfo{name: path.Base(clean), size: int64(len(data))}, nil
+}
+
+// Glob returns the files and directories matching pattern in the same
+// way as fs.Glob, using the syntax of path.Match. Use GlobRecursive
+// to match any number of directories.
+func (fs *Filesystem) Glob(pattern string) ([]string, error) {
+	pattern = strings.ToLower(pattern)
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	return fs.glob(func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}), nil
+}
+
+// GlobRecursive is Glob except that ** matches any number of
+// directories, so materials/**/*.vmt finds every vmt under materials
+func (fs *Filesystem) GlobRecursive(pattern string) ([]string, error) {
+	pattern = strings.ToLower(pattern)
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	patternParts := strings.Split(pattern, "/")
+
+	return fs.glob(func(name string) bool {
+		return globMatch(patternParts, strings.Split(name, "/"))
+	}), nil
+}
+
+// glob returns every file and directory that match accepts in order
+func (fs *Filesystem) glob(match func(name string) bool) []string {
+	var matches []string
+
+	for dir, entries := range fs.index() {
+		for name := range entries {
+			full := name
+			if dir != "." {
+				full = dir + "/" + name
+			}
+
+			if match(full) {
+				matches = append(matches, full)
+			}
+		}
+	}
+
+	sort.Strings(matches)
+
+	return matches
+}
+
+// globMatch matches the parts of a path against the parts of a pattern
+func globMatch(pattern []string, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		// Try matching any number of directories
+		for i := 0; i <= len(parts); i++ {
+			if globMatch(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+
+	return globMatch(pattern[1:], parts[1:])
+}
+
+// WalkDir walks the tree under root in the same way as fs.WalkDir
+func (fs *Filesystem) WalkDir(root string, fn iofs.WalkDirFunc) error {
+	return iofs.WalkDir(fs, root, fn)
+}
+
+func (fs *Filesystem) dirEntries(dir string, entries map[string]bool) []iofs.DirEntry {
+	ret := make([]iofs.DirEntry, 0, len(entries))
+
+	for name, isDir := range entries {
+		full := name
+		if dir != "." {
+			full = dir + "/" + name
+		}
+
+		ret = append(ret, &dirEntry{fs: fs, name: name, path: full, isDir: isDir})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name() < ret[j].Name()
+	})
+
+	return ret
+}
+
+// fileInfo describes a file or directory. Nothing that is mounted has
+// a useful modification time so it is always zero.
+type fileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func newDirInfo(dir string) fileInfo {
+	return fileInfo{name: path.Base(dir), isDir: true}
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.isDir }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+func (fi fileInfo) Mode() iofs.FileMode {
+	if fi.isDir {
+		return iofs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+type dirEntry struct {
+	fs    *Filesystem
+	name  string
+	path  string
+	isDir bool
+}
+
+func (de *dirEntry) Name() string { return de.name }
+func (de *dirEntry) IsDir() bool  { return de.isDir }
+
+func (de *dirEntry) Type() iofs.FileMode {
+	if de.isDir {
+		return iofs.ModeDir
+	}
+
+	return 0
+}
+
+func (de *dirEntry) Info() (iofs.FileInfo, error) {
+	return de.fs.Stat(de.path)
+}
+
+// memFile is a file that has been read into memory
+type memFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *memFile) Stat() (iofs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error                 { return nil }
+
+type dirFile struct {
+	info    fileInfo
+	entries []iofs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (iofs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error                 { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &iofs.PathError{Op: "read", Path: d.info.name, Err: iofs.ErrInvalid}
+}
+
+// ReadDir lists the directory in the same way as os.File.ReadDir
+func (d *dirFile) ReadDir(n int) ([]iofs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+
+	d.offset += n
+
+	return remaining[:n], nil
+}
@@ -0,0 +1,70 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestFilesystem(t *testing.T) *Filesystem {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "materials", "dev", "A.vmt"), "LightmappedGeneric\n{\n}\n")
+	writeFile(t, filepath.Join(dir, "materials", "dev", "sub", "b.vmt"), "LightmappedGeneric\n{\n}\n")
+	writeFile(t, filepath.Join(dir, "materials", "tools", "c.vtf"), "VTF")
+
+	fs := NewFilesystem()
+	fs.RegisterLocalDirectory(dir)
+
+	return fs
+}
+
+func TestIoFs(t *testing.T) {
+	fs := newTestFilesystem(t)
+
+	if err := fstest.TestFS(fs, "materials/dev/a.vmt", "materials/dev/sub/b.vmt", "materials/tools/c.vtf"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	fs := newTestFilesystem(t)
+
+	tests := []struct {
+		pattern   string
+		recursive bool
+		want      []string
+	}{
+		{"materials/*/*.vmt", false, []string{"materials/dev/a.vmt"}},
+		{"MATERIALS/DEV/*", false, []string{"materials/dev/a.vmt", "materials/dev/sub"}},
+		// ** is only special to GlobRecursive
+		{"materials/**/*.vmt", false, []string{"materials/dev/a.vmt"}},
+		{"materials/**/*.vmt", true, []string{"materials/dev/a.vmt", "materials/dev/sub/b.vmt"}},
+		{"**/c.vtf", true, []string{"materials/tools/c.vtf"}},
+		{"*.vmt", false, nil},
+	}
+
+	for _, test := range tests {
+		glob := fs.Glob
+		if test.recursive {
+			glob = fs.GlobRecursive
+		}
+
+		got, err := glob(test.pattern)
+		if err != nil {
+			t.Errorf("%s: %v", test.pattern, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s (recursive %v) matched %v, want %v", test.pattern, test.recursive, got, test.want)
+		}
+	}
+
+	if _, err := fs.Glob("materials/[dev"); err == nil {
+		t.Error("expected an error for a bad pattern")
+	}
+}
@@ -0,0 +1,262 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/galaco/bsp/lumps"
+	vpk "github.com/galaco/vpk2"
+)
+
+// Path ids that are given to mounts
+const (
+	PathIDGame     = "game"
+	PathIDMod      = "mod"
+	PathIDPlatform = "platform"
+	PathIDBsp      = "bsp"
+)
+
+type mountKind int
+
+const (
+	mountDirectory mountKind = iota
+	mountVpk
+	mountPakfile
+)
+
+// Mount is a single location that files are searched for in.
+// Pakfiles are searched first by priority, then everything
+// else in the order they were registered in.
+type Mount struct {
+	// Directory or vpk (without _dir.vpk) that this mount is for
+	Path string
+	// Lower case path ids from gameinfo such as game and mod
+	PathIDs []string
+
+	// Pakfiles with a higher priority are searched first
+	Priority int
+
+	kind    mountKind
+	vpk     *vpk.VPK
+	pakFile *lumps.Pakfile
+
+	// Contents of a pakfile mount made from zip data
+	zipFiles map[string]*zip.File
+
+	// Lower case path to the path on disk of every file in a
+	// directory mount. Built on first use so that lookups are
+	// case insensitive even on case sensitive filesystems.
+	files map[string]string
+
+	// Sorted lower case path of every file in this mount
+	paths []string
+}
+
+func newMount(kind mountKind, path string, pathIDs []string) *Mount {
+	return &Mount{
+		Path:    path,
+		PathIDs: splitPathIDs(pathIDs),
+		kind:    kind,
+	}
+}
+
+// splitPathIDs lower cases pathIDs and splits any that
+// gameinfo has combined with + (game+mod)
+func splitPathIDs(pathIDs []string) []string {
+	ids := []string{}
+
+	for _, id := range pathIDs {
+		for _, part := range strings.Split(id, "+") {
+			if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+				ids = append(ids, part)
+			}
+		}
+	}
+
+	return ids
+}
+
+// addPathIDs adds any of pathIDs that this mount does not already have
+func (m *Mount) addPathIDs(pathIDs ...string) {
+	for _, id := range splitPathIDs(pathIDs) {
+		if !m.HasPathID(id) {
+			m.PathIDs = append(m.PathIDs, id)
+		}
+	}
+}
+
+// HasPathID returns whether this mount can be searched with pathID
+func (m *Mount) HasPathID(pathID string) bool {
+	pathID = strings.ToLower(pathID)
+
+	for _, id := range m.PathIDs {
+		if id == pathID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Mount) String() string {
+	kind := "directory"
+
+	switch m.kind {
+	case mountVpk:
+		kind = "vpk"
+	case mountPakfile:
+		kind = "pakfile"
+	}
+
+	return kind + " " + m.Path + " (" + strings.Join(m.PathIDs, "+") + ")"
+}
+
+// open returns a stream for the normalised searchPath or nil
+// if this mount does not have it
+func (m *Mount) open(searchPath string) (io.Reader, error) {
+	switch m.kind {
+	case mountPakfile:
+		if m.zipFiles != nil {
+			f, ok := m.zipFiles[searchPath]
+			if !ok {
+				return nil, nil
+			}
+
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+
+			data, err := ioutil.ReadAll(r)
+			if err != nil {
+				return nil, err
+			}
+
+			return bytes.NewReader(data), nil
+		}
+
+		f, err := m.pakFile.GetFile(searchPath)
+		if err == nil && f != nil && len(f) != 0 {
+			return bytes.NewReader(f), nil
+		}
+
+	case mountDirectory:
+		diskPath, ok := m.localFiles()[searchPath]
+		if !ok {
+			return nil, nil
+		}
+
+		file, err := ioutil.ReadFile(diskPath)
+		if os.IsNotExist(err) {
+			// Removed since the index was built
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return bytes.NewReader(file), nil
+
+	case mountVpk:
+		entry := m.vpk.Entry(searchPath)
+		if entry != nil {
+			return entry.Open()
+		}
+	}
+
+	return nil, nil
+}
+
+// localFiles returns the index of a directory mount, building it if needed
+func (m *Mount) localFiles() map[string]string {
+	if m.files != nil {
+		return m.files
+	}
+
+	files := map[string]string{}
+
+	filepath.Walk(m.Path, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		if rel, err := filepath.Rel(m.Path, p); err == nil {
+			files[strings.ToLower(filepath.ToSlash(rel))] = p
+		}
+
+		return nil
+	})
+
+	m.files = files
+
+	return files
+}
+
+// Paths returns the lower case path of every file in this mount in order.
+// Pakfiles registered with RegisterPakFile cannot be listed.
+func (m *Mount) Paths() []string {
+	if m.paths != nil {
+		return m.paths
+	}
+
+	paths := []string{}
+
+	switch m.kind {
+	case mountDirectory:
+		for p := range m.localFiles() {
+			paths = append(paths, p)
+		}
+
+	case mountVpk:
+		for _, p := range m.vpk.Paths() {
+			paths = append(paths, strings.ToLower(NormalisePath(p)))
+		}
+
+	case mountPakfile:
+		for p := range m.zipFiles {
+			paths = append(paths, p)
+		}
+	}
+
+	sort.Strings(paths)
+	m.paths = paths
+
+	return paths
+}
+
+// has returns whether this mount has the normalised searchPath
+// without opening it
+func (m *Mount) has(searchPath string) bool {
+	switch m.kind {
+	case mountDirectory:
+		_, ok := m.localFiles()[searchPath]
+		return ok
+
+	case mountVpk:
+		return m.vpk.Entry(searchPath) != nil
+
+	case mountPakfile:
+		if m.zipFiles != nil {
+			_, ok := m.zipFiles[searchPath]
+			return ok
+		}
+
+		f, err := m.pakFile.GetFile(searchPath)
+		return err == nil && len(f) != 0
+	}
+
+	return false
+}
+
+// refresh throws away anything cached about the contents of this mount
+func (m *Mount) refresh() {
+	m.files = nil
+	m.paths = nil
+}
@@ -1,105 +1,382 @@
 package filesystem
 
 import (
+	"errors"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	keyvalues "github.com/galaco/KeyValues"
 	vpk "github.com/galaco/vpk2"
 )
 
+// Tokens that search paths in gameinfo.txt can start with
+var (
+	gameInfoPathToken         = regexp.MustCompile(`(?i)\|gameinfo_path\|`)
+	allSourceEnginePathsToken = regexp.MustCompile(`(?i)\|all_source_engine_paths\|`)
+	appIdToken                = regexp.MustCompile(`(?i)\|appid_(\d+)\|`)
+)
+
+// searchPath is a single entry of SearchPaths in gameinfo.txt
+type searchPath struct {
+	// Directory of the gameinfo.txt that listed this
+	basePath string
+
+	pathIDs string
+	path    string
+}
+
 // CreateFilesystemFromGameInfoDefinitions Reads game resource data paths
 // from gameinfo.txt
 // All games should ship with a gameinfo.txt, but it isn't actually mandatory.
-func CreateFromGameInfo(basePath string, gameInfo *keyvalues.KeyValue) *Filesystem {
+// basePath is the directory that gameinfo.txt is in, and opts can be nil.
+// Search paths that could not be mounted are returned as MountErrors,
+// everything else is mounted anyway.
+func CreateFromGameInfo(basePath string, gameInfo *keyvalues.KeyValue, opts *GameInfoOptions) (*Filesystem, error) {
 	fs := NewFilesystem()
-	fsNode, _ := gameInfo.Find("FileSystem")
 
-	searchPathsNode, _ := fsNode.Find("SearchPaths")
-	searchPaths, _ := searchPathsNode.Children()
 	basePath, _ = filepath.Abs(basePath)
-	basePath = strings.Replace(basePath, "\\", "/", -1)
+	basePath = NormalisePath(basePath)
+
+	errs := MountErrors{}
+
+	for _, sp := range readSearchPaths(basePath, gameInfo, opts, map[string]bool{}, &errs) {
+		fs.mountSearchPath(sp, opts, &errs)
+	}
+
+	if len(errs) != 0 {
+		return fs, errs
+	}
+
+	return fs, nil
+}
+
+// readSearchPaths returns the search paths of gameInfo whose conditionals are
+// met on this platform, followed by those of the files it includes with #base
+func readSearchPaths(basePath string, gameInfo *keyvalues.KeyValue, opts *GameInfoOptions, seen map[string]bool, errs *MountErrors) []searchPath {
+	results := []searchPath{}
+	bases := []string{}
+
+	// #base can be in any of the blocks leading to the search paths
+	nodes := []*keyvalues.KeyValue{gameInfo}
+	var searchPathsNode *keyvalues.KeyValue
+
+	// With a #base next to GameInfo there is more than one key at the top
+	// of the file, so the reader puts them all under $root
+	if gameInfo.Key() == "$root" {
+		if node, err := gameInfo.Find("GameInfo"); err == nil {
+			gameInfo = node
+			nodes = append(nodes, gameInfo)
+		}
+	}
+
+	fsNode, err := gameInfo.Find("FileSystem")
+	if err == nil {
+		nodes = append(nodes, fsNode)
+
+		if searchPathsNode, err = fsNode.Find("SearchPaths"); err == nil {
+			nodes = append(nodes, searchPathsNode)
+		}
+	}
+
+	for _, node := range nodes {
+		children, _ := node.Children()
+
+		for _, kv := range children {
+			key, keyCondition := splitConditional(kv.Key())
+			value, _ := kv.AsString()
+			value, valueCondition := splitConditional(value)
+
+			if keyCondition != "" && !conditionMet(keyCondition, opts.platform()) ||
+				valueCondition != "" && !conditionMet(valueCondition, opts.platform()) {
+				continue
+			}
+
+			if strings.EqualFold(key, "#base") {
+				bases = append(bases, value)
+				continue
+			}
 
-	for _, searchPath := range searchPaths {
-		kv := searchPath
-		path, _ := kv.AsString()
-		path = strings.Trim(path, " ")
+			// Only the children of SearchPaths are search paths
+			if node == searchPathsNode {
+				results = append(results, searchPath{basePath, key, value})
+			}
+		}
+	}
+
+	// Without either there is nothing to mount
+	if fsNode == nil && len(bases) == 0 {
+		*errs = append(*errs, &MountError{"FileSystem", basePath, errors.New("gameinfo has no FileSystem block")})
+	}
 
-		// Current directory
-		gameInfoPathRegex := regexp.MustCompile(`(?i)\|gameinfo_path\|`)
-		if gameInfoPathRegex.MatchString(path) {
-			path = gameInfoPathRegex.ReplaceAllString(path, basePath+"/")
+	for _, base := range bases {
+		p := NormalisePath(strings.Trim(base, "\""))
+		if !isAbsolutePath(p) {
+			p = basePath + "/" + p
 		}
+		p = path.Clean(p)
 
-		// Executable directory
-		allSourceEnginePathsRegex := regexp.MustCompile(`(?i)\|all_source_engine_paths\|`)
-		if allSourceEnginePathsRegex.MatchString(path) {
-			path = allSourceEnginePathsRegex.ReplaceAllString(path, basePath+"/../")
+		if seen[p] {
+			continue
 		}
-		if strings.Contains(strings.ToLower(kv.Key()), "mod") && !strings.HasPrefix(path, basePath) {
-			path = basePath + "/../" + path
+		seen[p] = true
+
+		included, err := readKeyValues(p)
+		if err != nil {
+			*errs = append(*errs, &MountError{"#base", base, err})
+			continue
+		}
+
+		// Paths in the included file are relative to it
+		for _, sp := range readSearchPaths(path.Dir(p), included, opts, seen, errs) {
+			if !containsSearchPath(results, sp) {
+				results = append(results, sp)
+			}
 		}
+	}
+
+	return results
+}
+
+func containsSearchPath(searchPaths []searchPath, sp searchPath) bool {
+	for _, other := range searchPaths {
+		if other == sp {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readKeyValues(filename string) (*keyvalues.KeyValue, error) {
+	stream, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	reader := keyvalues.NewReader(stream)
+
+	kv, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kv, nil
+}
+
+// isAbsolutePath checks for absolute paths from any platform,
+// as gameinfo.txt could have been written on either
+func isAbsolutePath(p string) bool {
+	return strings.HasPrefix(p, "/") || len(p) > 1 && p[1] == ':' || filepath.IsAbs(p)
+}
 
-		// Strip vpk extension, then load it
-		path = strings.Trim(strings.Trim(path, " "), "\"")
-		if strings.HasSuffix(path, ".vpk") {
-			path = strings.Replace(path, ".vpk", "", 1)
-			opener := vpk.MultiVPK(path)
-			vpkHandle, err := vpk.Open(opener)
-			if err != nil {
-				// TODO log error
+// expandSearchPath turns a path from gameinfo.txt into a full path
+func expandSearchPath(sp searchPath, opts *GameInfoOptions) (string, error) {
+	p := NormalisePath(strings.Trim(strings.TrimSpace(sp.path), "\""))
+
+	switch {
+	case gameInfoPathToken.MatchString(p):
+		p = gameInfoPathToken.ReplaceAllLiteralString(p, sp.basePath+"/")
+
+	case allSourceEnginePathsToken.MatchString(p):
+		p = allSourceEnginePathsToken.ReplaceAllLiteralString(p, sp.basePath+"/../")
+
+	case appIdToken.MatchString(p):
+		appId, _ := strconv.Atoi(appIdToken.FindStringSubmatch(p)[1])
+
+		if opts == nil || opts.ResolveAppId == nil {
+			return "", errors.New("no way to find where app " + strconv.Itoa(appId) + " is installed")
+		}
+
+		dir, err := opts.ResolveAppId(appId)
+		if err != nil {
+			return "", err
+		}
+
+		p = appIdToken.ReplaceAllLiteralString(p, NormalisePath(dir)+"/")
+
+	case !isAbsolutePath(p):
+		// Relative to the directory that the game directory is in
+		p = sp.basePath + "/../" + p
+	}
+
+	// Clean would remove the trailing /
+	if strings.HasSuffix(p, "/*") {
+		return path.Clean(strings.TrimSuffix(p, "/*")) + "/*", nil
+	}
+
+	return path.Clean(p), nil
+}
+
+// mountSearchPath mounts a search path from gameinfo.txt, adding
+// its path ids to the existing mount if it is already mounted
+func (fs *Filesystem) mountSearchPath(sp searchPath, opts *GameInfoOptions, errs *MountErrors) {
+	// Low violence content is only used when asked for
+	if strings.EqualFold(sp.pathIDs, "game_lv") {
+		return
+	}
+
+	p, err := expandSearchPath(sp, opts)
+	if err != nil {
+		*errs = append(*errs, &MountError{sp.pathIDs, sp.path, err})
+		return
+	}
+
+	switch {
+	case strings.HasSuffix(p, "/*"):
+		fs.mountWildcard(strings.TrimSuffix(p, "/*"), sp, errs)
+
+	case strings.HasSuffix(strings.ToLower(p), ".vpk"):
+		// pak01_dir.vpk and pak01.vpk both mean the pak01 set
+		prefix := p[:len(p)-len(".vpk")]
+		if strings.HasSuffix(strings.ToLower(prefix), "_dir") {
+			prefix = prefix[:len(prefix)-len("_dir")]
+		}
+
+		if err := fs.mountVpk(prefix, sp.pathIDs); err != nil {
+			*errs = append(*errs, &MountError{sp.pathIDs, sp.path, err})
+		}
+
+	default:
+		info, err := os.Stat(p)
+		if err == nil && !info.IsDir() {
+			err = errors.New("not a directory")
+		}
+
+		if err != nil {
+			*errs = append(*errs, &MountError{sp.pathIDs, sp.path, err})
+			return
+		}
+
+		if m := fs.findMount(mountDirectory, p); m != nil {
+			m.addPathIDs(sp.pathIDs)
+			return
+		}
+
+		fs.RegisterLocalDirectory(p, sp.pathIDs)
+	}
+}
+
+// mountWildcard mounts every directory and vpk in dir, which is how
+// addon and custom folders are listed. It is fine for dir not to exist.
+func (fs *Filesystem) mountWildcard(dir string, sp searchPath, errs *MountErrors) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, f := range files {
+		p := dir + "/" + f.Name()
+
+		switch {
+		case f.IsDir():
+			if m := fs.findMount(mountDirectory, p); m != nil {
+				m.addPathIDs(sp.pathIDs)
 				continue
 			}
-			fs.RegisterVpk(path, vpkHandle)
-		} else {
-			// wildcard suffixes not useful
-			if strings.HasSuffix(path, "/*") {
-				path = strings.Replace(path, "/*", "", -1)
+
+			fs.RegisterLocalDirectory(p, sp.pathIDs)
+
+		case strings.HasSuffix(strings.ToLower(f.Name()), "_dir.vpk"):
+			if err := fs.mountVpk(p[:len(p)-len("_dir.vpk")], sp.pathIDs); err != nil {
+				*errs = append(*errs, &MountError{sp.pathIDs, sp.path, err})
 			}
-			fs.RegisterLocalDirectory(path)
 		}
 	}
+}
+
+// mountVpk mounts the vpk set at prefix (without _dir.vpk),
+// adding pathIDs to it if it is already mounted
+func (fs *Filesystem) mountVpk(prefix string, pathIDs string) error {
+	if m := fs.findMount(mountVpk, prefix); m != nil {
+		m.addPathIDs(pathIDs)
+		return nil
+	}
 
-	return fs
+	v, err := vpk.Open(vpk.MultiVPK(prefix))
+	if err != nil {
+		return err
+	}
+
+	fs.RegisterVpk(prefix, v, pathIDs)
+
+	return nil
 }
 
-func CreateFromGameDir(path string, gameInfo *keyvalues.KeyValue) *Filesystem {
+// CreateFromGameDir creates a filesystem from the gameinfo.txt of the game
+// in path, along with the platform directory and every vpk in the mounted
+// directories. Like CreateFromGameInfo anything that could not be mounted
+// is returned as MountErrors.
+func CreateFromGameDir(gameDir string, gameInfo *keyvalues.KeyValue, opts *GameInfoOptions) (*Filesystem, error) {
 
 	// Register GameInfo.txt referenced resource paths
 	// Filesystem module needs to know about all the possible resource
 	// locations it can search.
-	fs := CreateFromGameInfo(path, gameInfo)
+	fs, err := CreateFromGameInfo(gameDir, gameInfo, opts)
+
+	errs := MountErrors{}
+	errors.As(err, &errs)
+
+	// Make sure to also load the platform dir, gameinfo usually has it already
+	gameDir, _ = filepath.Abs(gameDir)
+	platformDir := path.Clean(NormalisePath(gameDir) + "/../platform")
+
+	if m := fs.findMount(mountDirectory, platformDir); m != nil {
+		m.addPathIDs(PathIDPlatform)
+	} else {
+		fs.RegisterLocalDirectory(platformDir, PathIDPlatform)
+	}
 
-	// Make sure to also load the platform dir
-	fs.RegisterLocalDirectory(path + "/../platform")
+	// Now try and load all of the vpks that are in those directories.
+	// They are searched straight after their directory.
+	for _, dir := range append([]*Mount(nil), fs.Mounts()...) {
+		if dir.kind != mountDirectory {
+			continue
+		}
 
-	// Now try and load all of the vpks that are in those directories
-	for _, x := range fs.EnumerateResourcePaths() {
+		after := dir
+
+		x := dir.Path
 		files, err := ioutil.ReadDir(x)
 
 		if err != nil {
-			// panic(err)
 			continue
 		}
 
 		for _, f := range files {
 			if strings.HasSuffix(f.Name(), "_dir.vpk") {
 				nameNoSuffix := x + "/" + f.Name()[:len(f.Name())-8]
+				if fs.findMount(mountVpk, nameNoSuffix) != nil {
+					// Already listed in gameinfo
+					continue
+				}
+
 				opener := vpk.MultiVPK(nameNoSuffix)
 				v, err := vpk.Open(opener)
 
 				if err != nil {
-					panic(err)
+					errs = append(errs, &MountError{strings.Join(dir.PathIDs, "+"), x + "/" + f.Name(), err})
+					continue
 				}
 
-				fs.RegisterVpk(nameNoSuffix, v)
+				m := newMount(mountVpk, nameNoSuffix, dir.PathIDs)
+				m.vpk = v
+				fs.insertMount(after, m)
+				after = m
 			}
 		}
 
 		// TODO: we also need to load all the vpks that arent part of a dir pack
 	}
 
-	return fs
+	if len(errs) != 0 {
+		return fs, errs
+	}
+
+	return fs, nil
 }
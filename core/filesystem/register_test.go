@@ -0,0 +1,156 @@
+package filesystem
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, filename string, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateFromGameInfoBase(t *testing.T) {
+	root := t.TempDir()
+
+	for _, dir := range []string{"mod", "hl2", "shared"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(t, filepath.Join(root, "mod", "gameinfo.txt"), `"#base" "gameinfo_shared.txt"
+"GameInfo"
+{
+	game "Mod"
+	FileSystem
+	{
+		SearchPaths
+		{
+			game+mod	|gameinfo_path|.
+			game	hl2
+		}
+	}
+}
+`)
+
+	writeFile(t, filepath.Join(root, "mod", "gameinfo_shared.txt"), `"GameInfo"
+{
+	FileSystem
+	{
+		SearchPaths
+		{
+			game	shared
+			game	hl2
+		}
+	}
+}
+`)
+
+	gameInfo, err := readKeyValues(filepath.Join(root, "mod", "gameinfo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gameInfo.Key() != "$root" {
+		t.Fatalf("expected #base to put the gameinfo under $root, got %s", gameInfo.Key())
+	}
+
+	fs, err := CreateFromGameInfo(filepath.Join(root, "mod"), gameInfo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := NormalisePath(root)
+	want := []struct {
+		path    string
+		pathIDs []string
+	}{
+		{base + "/mod", []string{"game", "mod"}},
+		{base + "/hl2", []string{"game"}},
+		{base + "/shared", []string{"game"}},
+	}
+
+	mounts := fs.Mounts()
+	if len(mounts) != len(want) {
+		t.Fatalf("got %d mounts %v, want %d", len(mounts), mounts, len(want))
+	}
+
+	for i, w := range want {
+		if mounts[i].Path != w.path {
+			t.Errorf("mount %d is %s, want %s", i, mounts[i].Path, w.path)
+		}
+
+		for _, id := range w.pathIDs {
+			if !mounts[i].HasPathID(id) {
+				t.Errorf("mount %s is missing path id %s", mounts[i].Path, id)
+			}
+		}
+	}
+}
+
+func TestCreateFromGameInfoNoFileSystem(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "mod", "gameinfo.txt"), `"GameInfo"
+{
+	game "Mod"
+}
+`)
+
+	gameInfo, err := readKeyValues(filepath.Join(root, "mod", "gameinfo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CreateFromGameInfo(filepath.Join(root, "mod"), gameInfo, nil)
+
+	errs := MountErrors{}
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].PathIDs != "FileSystem" {
+		t.Errorf("expected a missing FileSystem error, got %v", err)
+	}
+}
+
+func TestCreateFromGameInfoMissingBase(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "mod", "gameinfo.txt"), `"#base" "missing.txt"
+"GameInfo"
+{
+	FileSystem
+	{
+		SearchPaths
+		{
+			game+mod	|gameinfo_path|.
+		}
+	}
+}
+`)
+
+	gameInfo, err := readKeyValues(filepath.Join(root, "mod", "gameinfo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := CreateFromGameInfo(filepath.Join(root, "mod"), gameInfo, nil)
+
+	errs := MountErrors{}
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].PathIDs != "#base" {
+		t.Errorf("expected a #base error, got %v", err)
+	}
+
+	// The rest of the gameinfo is still mounted
+	if len(fs.Mounts()) != 1 {
+		t.Errorf("got %d mounts, want 1", len(fs.Mounts()))
+	}
+}
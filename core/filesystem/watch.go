@@ -0,0 +1,207 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watcher notices when files that have been read from a filesystem are
+// changed, created or removed in its directory mounts. Only files that
+// have been read are checked so that a game with a lot of loose files
+// does not have to be walked every time. It polls as nothing else works
+// everywhere.
+type Watcher struct {
+	fs         *Filesystem
+	extensions []string
+	interval   time.Duration
+
+	mu    sync.Mutex
+	files map[string]*watchedFile
+
+	changes  chan []string
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// watchedFile is a file that has been read and how it looked when it was last checked
+type watchedFile struct {
+	// Where the file could be on disk in search order
+	diskPaths []string
+
+	// Which of diskPaths it was found at, -1 if none
+	found   int
+	modTime time.Time
+	size    int64
+}
+
+// Watch starts watching files ending in any of extensions (such as .vmt)
+// that are read from fs from now on, checking every interval.
+// Only one watcher is given the files that are read.
+func (fs *Filesystem) Watch(interval time.Duration, extensions ...string) *Watcher {
+	w := &Watcher{
+		fs:       fs,
+		interval: interval,
+		files:    map[string]*watchedFile{},
+		changes:  make(chan []string, 1),
+		stop:     make(chan struct{}),
+	}
+
+	for _, ext := range extensions {
+		w.extensions = append(w.extensions, strings.ToLower(ext))
+	}
+
+	fs.watcher = w
+
+	go w.run()
+
+	return w
+}
+
+// Changed returns the lower case path of every file that has changed since
+// it was last called, in the same form as GetFile takes. It does not block.
+func (w *Watcher) Changed() []string {
+	changed := []string{}
+
+	for {
+		select {
+		case files := <-w.changes:
+			for _, f := range files {
+				if !containsString(changed, f) {
+					changed = append(changed, f)
+				}
+			}
+		default:
+			sort.Strings(changed)
+			return changed
+		}
+	}
+}
+
+// Stop stops watching
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		if w.fs.watcher == w {
+			w.fs.watcher = nil
+		}
+
+		close(w.stop)
+	})
+}
+
+// add starts watching searchPath if it is not already watched. This is called
+// on whatever is reading from the filesystem so the mounts are safe to use.
+func (w *Watcher) add(searchPath string) {
+	if !w.watching(searchPath) {
+		return
+	}
+
+	w.mu.Lock()
+	_, ok := w.files[searchPath]
+	w.mu.Unlock()
+
+	if ok {
+		return
+	}
+
+	f := &watchedFile{}
+
+	for _, m := range w.fs.mounts {
+		if m.kind != mountDirectory {
+			continue
+		}
+
+		// Use the real case of the file if there is one
+		diskPath, ok := m.localFiles()[searchPath]
+		if !ok {
+			diskPath = filepath.Join(m.Path, filepath.FromSlash(searchPath))
+		}
+
+		f.diskPaths = append(f.diskPaths, diskPath)
+	}
+
+	f.check()
+
+	w.mu.Lock()
+	w.files[searchPath] = f
+	w.mu.Unlock()
+}
+
+func (w *Watcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		changed := []string{}
+
+		w.mu.Lock()
+		for searchPath, f := range w.files {
+			if f.check() {
+				changed = append(changed, searchPath)
+			}
+		}
+		w.mu.Unlock()
+
+		if len(changed) == 0 {
+			continue
+		}
+
+		select {
+		case w.changes <- changed:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// check looks at the file on disk again
+// and returns whether it has changed
+func (f *watchedFile) check() bool {
+	found := -1
+	var modTime time.Time
+	var size int64
+
+	for i, p := range f.diskPaths {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			found, modTime, size = i, info.ModTime(), info.Size()
+			break
+		}
+	}
+
+	changed := found != f.found || !modTime.Equal(f.modTime) || size != f.size
+
+	f.found, f.modTime, f.size = found, modTime, size
+
+	return changed
+}
+
+func (w *Watcher) watching(p string) bool {
+	ext := strings.ToLower(filepath.Ext(p))
+
+	for _, watched := range w.extensions {
+		if ext == watched {
+			return true
+		}
+	}
+
+	return len(w.extensions) == 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, other := range list {
+		if other == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchReadFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "materials", "dev", "Read.vmt"), "LightmappedGeneric\n{\n}\n")
+	writeFile(t, filepath.Join(dir, "materials", "dev", "unread.vmt"), "LightmappedGeneric\n{\n}\n")
+
+	fs := NewFilesystem()
+	fs.RegisterLocalDirectory(dir)
+
+	w := fs.Watch(10*time.Millisecond, ExtensionVmt)
+	defer w.Stop()
+
+	if _, err := fs.GetFile("materials/dev/read.vmt"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(dir, "materials", "dev", "Read.vmt"), "UnlitGeneric\n{\n}\n")
+	writeFile(t, filepath.Join(dir, "materials", "dev", "unread.vmt"), "UnlitGeneric\n{\n}\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	changed := []string{}
+
+	for len(changed) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		changed = w.Changed()
+	}
+
+	if len(changed) != 1 || changed[0] != "materials/dev/read.vmt" {
+		t.Errorf("changed files are %v, want only the one that was read", changed)
+	}
+
+	// Removing it counts as a change too
+	if err := os.Remove(filepath.Join(dir, "materials", "dev", "Read.vmt")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed = []string{}
+	for len(changed) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		changed = w.Changed()
+	}
+
+	if len(changed) != 1 || changed[0] != "materials/dev/read.vmt" {
+		t.Errorf("changed files after removing are %v", changed)
+	}
+}
@@ -6,7 +6,7 @@ require (
 	github.com/emily33901/forgery/core/filesystem v0.0.0-20200417143503-99942b2c0535
 	github.com/emily33901/forgery/core/manager v0.0.0-20200417143503-99942b2c0535
 	github.com/emily33901/forgery/core/textures v0.0.0
-	github.com/golang-source-engine/vmt v0.1.2 // indirect
+	github.com/golang-source-engine/vmt v0.1.2
 )
 
 replace github.com/emily33901/forgery/core/textures => ../textures
@@ -2,7 +2,10 @@ package materials
 
 import (
 	"fmt"
+	"path"
+	"strings"
 
+	"github.com/emily33901/forgery/core/events"
 	"github.com/emily33901/forgery/core/filesystem"
 	"github.com/emily33901/forgery/core/manager"
 	"github.com/emily33901/forgery/core/textures"
@@ -76,6 +79,7 @@ func loadMaterial(path string, fs *filesystem.Filesystem) (*Material, error) {
 	mat.Textures.Albedo, err = textures.Load(vtfTexturePath, fs)
 
 	if err != nil {
+		events.UnsubscribeAllID(mat)
 		return nil, err
 	}
 
@@ -83,9 +87,89 @@ func loadMaterial(path string, fs *filesystem.Filesystem) (*Material, error) {
 		mat.Textures.Normal, err = textures.Load(mat.Props.Bumpmap, fs)
 
 		if err != nil {
+			events.UnsubscribeAllID(mat)
 			return nil, err
 		}
 	}
 
+	// The albedo might already have been loaded by another
+	// material in which case TextureLoaded is not dispatched again
+	mat.loaded = true
+
 	return mat, nil
 }
+
+// Reload reloads whatever comes from file, which is a vmt or vtf path
+// such as materials/dev/dev_measuregeneric01b.vmt, and dispatches
+// MaterialReloaded for every material that changed.
+// It has to be called on the render thread.
+func Reload(file string, fs *filesystem.Filesystem) {
+	file = strings.ToLower(filesystem.NormalisePath(file))
+
+	switch path.Ext(file) {
+	case filesystem.ExtensionVtf:
+		reloaded := textures.Reload(file, fs)
+
+		for _, k := range materialsManager.Keys() {
+			mat := materialsManager.Get(k).(*Material)
+
+			for _, tex := range reloaded {
+				if mat.Textures.Albedo == tex || mat.Textures.Normal == tex {
+					mat.g3nMaterial = nil
+					events.Dispatch(MaterialReloaded, &MaterialReloadedEvent{k, mat})
+					break
+				}
+			}
+		}
+
+	case filesystem.ExtensionVmt:
+		// Every name that the material was loaded with uses the new one
+		var old, mat *Material
+
+		for _, k := range materialsManager.Keys() {
+			if materialFile(k) != file {
+				continue
+			}
+
+			if old == nil {
+				old = materialsManager.Get(k).(*Material)
+
+				fmt.Println("Reloading", old.FilePath())
+
+				var err error
+				if mat, err = loadMaterial(old.FilePath(), fs); err != nil {
+					// Keep using what was there before
+					return
+				}
+			}
+
+			materialsManager.NewCustom(k, mat)
+			events.Dispatch(MaterialReloaded, &MaterialReloadedEvent{k, mat})
+		}
+
+		if old != nil {
+			// The old material is no longer used by anything
+			events.UnsubscribeAllID(old)
+		}
+	}
+}
+
+// Exists returns whether fs has a vmt for the material name
+func Exists(name string, fs *filesystem.Filesystem) bool {
+	return fs.Exists(materialFile(name))
+}
+
+// materialFile returns the lower case path of the vmt for the material name
+func materialFile(name string) string {
+	file := strings.ToLower(filesystem.NormalisePath(name))
+
+	if !strings.HasPrefix(file, filesystem.BasePathMaterial) {
+		file = filesystem.BasePathMaterial + file
+	}
+
+	if !strings.HasSuffix(file, filesystem.ExtensionVmt) {
+		file += filesystem.ExtensionVmt
+	}
+
+	return file
+}
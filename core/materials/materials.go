@@ -12,6 +12,26 @@ import (
 	"github.com/golang-source-engine/vmt"
 )
 
+const (
+	// Events
+
+	// MaterialLoaded tells other systems that a materials textures have loaded
+	MaterialLoaded = "Materials.Loaded"
+	// MaterialReloaded tells other systems that a material or one
+	// of its textures has changed on disk
+	MaterialReloaded = "Materials.Reloaded"
+)
+
+type MaterialLoadedEvent struct {
+	Path string
+	Mat  *Material
+}
+
+type MaterialReloadedEvent struct {
+	Path string
+	Mat  *Material
+}
+
 // Material
 type Material struct {
 	Props *vmt.Properties
@@ -39,12 +59,18 @@ func (mat *Material) Loaded() bool {
 func (mat *Material) TextureLoaded(ev *textures.TextureLoadedEvent) {
 	if ev.Err != nil {
 		fmt.Println("Failed to load texture", ev.Err)
+		return
 	}
 
 	if ev.Path == mat.Textures.albedoPath {
 		fmt.Println("Loaded", ev.Path)
 		mat.Textures.Albedo = ev.Tex
 		mat.loaded = true
+
+		// Anything using this material needs to know its size now
+		events.Dispatch(MaterialLoaded, &MaterialLoadedEvent{
+			mat.filePath, mat,
+		})
 	}
 
 	if ev.Path == mat.Textures.normalPath {
@@ -130,7 +156,9 @@ func NewMaterial(filePath string, props *vmt.Properties) (mat *Material) {
 		Props:    props,
 	}
 
-	events.Subscribe(textures.TextureLoaded, func(_ string, evData interface{}) {
+	// Subscribed with mat as the id so that it can be
+	// unsubscribed when mat is replaced by a reload
+	events.SubscribeID(textures.TextureLoaded, mat, func(_ string, evData interface{}) {
 		ev := evData.(*textures.TextureLoadedEvent)
 		mat.TextureLoaded(ev)
 	})
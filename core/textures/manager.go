@@ -2,6 +2,7 @@ package textures
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/emily33901/forgery/core/events"
 	"github.com/emily33901/forgery/core/filesystem"
@@ -13,6 +14,8 @@ const (
 
 	// TextureLoaded tells other systems that a texture is loaded
 	TextureLoaded = "Textures.Loaded"
+	// TextureReloaded tells other systems that a texture has changed on disk
+	TextureReloaded = "Textures.Reloaded"
 )
 
 type TextureLoadedEvent struct {
@@ -21,6 +24,12 @@ type TextureLoadedEvent struct {
 	Err  error
 }
 
+type TextureReloadedEvent struct {
+	Path string
+	Tex  *Texture
+	Err  error
+}
+
 var textureManager *manager.Manager = manager.NewManager("")
 
 func Load(path string, fs *filesystem.Filesystem) (*Texture, error) {
@@ -64,3 +73,42 @@ func normaliseTexturePath(path string) string {
 
 	return path
 }
+
+// Reload reloads every loaded texture that comes from file (such as
+// materials/dev/dev_measuregeneric01b.vtf) and returns them. Textures are
+// changed in place so anything holding one only has to upload it again.
+// It has to be called on the render thread as the old uploads are freed.
+func Reload(file string, fs *filesystem.Filesystem) []*Texture {
+	reloaded := []*Texture{}
+
+	for _, k := range textureManager.Keys() {
+		tex := textureManager.Get(k).(*Texture)
+
+		if !strings.EqualFold(filesystem.NormalisePath(tex.FilePath()), file) || containsTexture(reloaded, tex) {
+			continue
+		}
+
+		fmt.Println("Reloading", tex.FilePath())
+
+		err := tex.reload(fs)
+		if err == nil {
+			reloaded = append(reloaded, tex)
+		}
+
+		events.Dispatch(TextureReloaded, &TextureReloadedEvent{
+			k, tex, err,
+		})
+	}
+
+	return reloaded
+}
+
+func containsTexture(list []*Texture, tex *Texture) bool {
+	for _, other := range list {
+		if other == tex {
+			return true
+		}
+	}
+
+	return false
+}
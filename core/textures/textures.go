@@ -122,6 +122,28 @@ func (tex *Texture) G3nTexture() *texture.Texture2D {
 	return tex.g3nTexture
 }
 
+// reload reads the size of this texture again and frees what was
+// uploaded so that the next G3nTexture uses the new contents.
+// It has to be called on the render thread.
+func (tex *Texture) reload(fs *filesystem.Filesystem) error {
+	fresh, err := readVtf(tex.filePath, fs)
+	if err != nil {
+		return err
+	}
+
+	tex.fileSystem = fs
+	tex.width = fresh.width
+	tex.height = fresh.height
+	tex.Translucent = false
+
+	if tex.g3nTexture != nil {
+		tex.g3nTexture.Dispose()
+		tex.g3nTexture = nil
+	}
+
+	return nil
+}
+
 func (tex *Texture) EvictFromMainMemory() {
 	// This will trigger gc to evict this memory
 	tex.vtf = nil
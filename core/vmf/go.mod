@@ -2,6 +2,10 @@ module github.com/emily33901/forgery/core/vmf
 
 go 1.14
 
-require github.com/emily33901/forgery/core/world v0.0.0
+require (
+	github.com/emily33901/forgery/core/world v0.0.0
+	github.com/g3n/engine v0.1.0
+	github.com/galaco/vmf v1.0.0
+)
 
 replace github.com/emily33901/forgery/core/world => ../world/
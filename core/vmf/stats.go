@@ -0,0 +1,183 @@
+package vmf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/emily33901/forgery/core/world"
+	"github.com/g3n/engine/math32"
+	"github.com/galaco/vmf"
+)
+
+// Stats is a summary of what is in a map
+type Stats struct {
+	// Solids in the world and solids belonging to brush entities
+	WorldSolids  int `json:"worldSolids"`
+	EntitySolids int `json:"entitySolids"`
+
+	Faces         int `json:"faces"`
+	ToolFaces     int `json:"toolFaces"`
+	Displacements int `json:"displacements"`
+
+	Entities       int            `json:"entities"`
+	EntityClasses  map[string]int `json:"entityClasses"`
+	MaterialSolids map[string]int `json:"materialSolids"`
+	MaterialFaces  map[string]int `json:"materialFaces"`
+
+	// Bounds of every solid
+	Mins math32.Vector3 `json:"mins"`
+	Maxs math32.Vector3 `json:"maxs"`
+}
+
+// Stats counts what is in this vmf
+func (vmf *Vmf) Stats() *Stats {
+	return newStats(vmf.world.Solids(), vmf.world.Entities())
+}
+
+// LoadStats counts what is in the vmf at filepath without creating a World,
+// so it can be used without a window or event dispatcher
+func LoadStats(filepath string) (*Stats, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := vmf.NewReader(file)
+	importable, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	solids, err := loadSolids(&importable.World)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := loadEntities(&importable.Entities)
+	if err != nil {
+		return nil, err
+	}
+
+	worldSolids := make([]*world.Solid, len(solids))
+	for i := range solids {
+		worldSolids[i] = &solids[i]
+	}
+
+	entityPointers := make([]*Entity, len(entities))
+	for i := range entities {
+		entityPointers[i] = &entities[i]
+	}
+
+	return newStats(worldSolids, entityPointers), nil
+}
+
+func newStats(worldSolids []*world.Solid, entities []*Entity) *Stats {
+	stats := &Stats{
+		EntityClasses:  map[string]int{},
+		MaterialSolids: map[string]int{},
+		MaterialFaces:  map[string]int{},
+	}
+
+	bounds := math32.Box3{}
+	bounds.MakeEmpty()
+
+	countSolid := func(s *world.Solid) {
+		b := s.Bounds()
+		bounds.Union(&b)
+
+		used := map[string]bool{}
+
+		for _, side := range s.Sides {
+			material := strings.ToLower(side.Material)
+
+			stats.Faces++
+			stats.MaterialFaces[material]++
+
+			if strings.HasPrefix(material, "tools/") {
+				stats.ToolFaces++
+			}
+
+			if side.DispInfo != nil {
+				stats.Displacements++
+			}
+
+			if !used[material] {
+				used[material] = true
+				stats.MaterialSolids[material]++
+			}
+		}
+	}
+
+	for _, s := range worldSolids {
+		stats.WorldSolids++
+		countSolid(s)
+	}
+
+	for _, e := range entities {
+		stats.Entities++
+		stats.EntityClasses[e.Classname]++
+
+		for i := range e.Solids {
+			stats.EntitySolids++
+			countSolid(&e.Solids[i])
+		}
+	}
+
+	if !bounds.Empty() {
+		stats.Mins = bounds.Min
+		stats.Maxs = bounds.Max
+	}
+
+	return stats
+}
+
+// JSON returns the stats as indented json
+func (s *Stats) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "\t")
+}
+
+// String returns the stats as a human readable report
+func (s *Stats) String() string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "Solids:        %d (%d world, %d entity)\n", s.WorldSolids+s.EntitySolids, s.WorldSolids, s.EntitySolids)
+	fmt.Fprintf(b, "Faces:         %d (%d tool)\n", s.Faces, s.ToolFaces)
+	fmt.Fprintf(b, "Displacements: %d\n", s.Displacements)
+	fmt.Fprintf(b, "Entities:      %d\n", s.Entities)
+	fmt.Fprintf(b, "Materials:     %d\n", len(s.MaterialFaces))
+	fmt.Fprintf(b, "Bounds:        (%g %g %g) (%g %g %g)\n", s.Mins.X, s.Mins.Y, s.Mins.Z, s.Maxs.X, s.Maxs.Y, s.Maxs.Z)
+
+	fmt.Fprintf(b, "\nEntity classes:\n")
+	for _, class := range sortedByCount(s.EntityClasses) {
+		fmt.Fprintf(b, "%8d %s\n", s.EntityClasses[class], class)
+	}
+
+	fmt.Fprintf(b, "\nMaterials (faces, solids):\n")
+	for _, material := range sortedByCount(s.MaterialFaces) {
+		fmt.Fprintf(b, "%8d %8d %s\n", s.MaterialFaces[material], s.MaterialSolids[material], material)
+	}
+
+	return b.String()
+}
+
+// sortedByCount returns the keys of counts with the largest count first
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
@@ -0,0 +1,50 @@
+package vmf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/g3n/engine/math32"
+)
+
+func TestLoadStats(t *testing.T) {
+	stats, err := LoadStats("testdata/stats.vmf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.WorldSolids != 1 || stats.EntitySolids != 1 {
+		t.Errorf("solids = %d world %d entity, want 1 and 1", stats.WorldSolids, stats.EntitySolids)
+	}
+
+	if stats.Faces != 12 || stats.ToolFaces != 7 {
+		t.Errorf("faces = %d (%d tool), want 12 (7 tool)", stats.Faces, stats.ToolFaces)
+	}
+
+	if stats.Entities != 2 || stats.EntityClasses["func_detail"] != 1 || stats.EntityClasses["info_player_start"] != 1 {
+		t.Errorf("entity classes = %v", stats.EntityClasses)
+	}
+
+	if stats.MaterialFaces["tools/toolsnodraw"] != 7 || stats.MaterialSolids["tools/toolsnodraw"] != 2 {
+		t.Errorf("nodraw = %d faces %d solids, want 7 and 2",
+			stats.MaterialFaces["tools/toolsnodraw"], stats.MaterialSolids["tools/toolsnodraw"])
+	}
+
+	if stats.Mins != (math32.Vector3{-64, -64, 0}) || stats.Maxs != (math32.Vector3{64, 64, 96}) {
+		t.Errorf("bounds = %v %v", stats.Mins, stats.Maxs)
+	}
+
+	if !strings.Contains(stats.String(), "Solids:        2 (1 world, 1 entity)") {
+		t.Errorf("report is missing solid counts:\n%s", stats)
+	}
+
+	if _, err := stats.JSON(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadStatsMissingFile(t *testing.T) {
+	if _, err := LoadStats("testdata/missing.vmf"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
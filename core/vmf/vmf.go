@@ -9,7 +9,6 @@ import (
 	"github.com/emily33901/forgery/core/world"
 
 	"github.com/g3n/engine/math32"
-	"github.com/galaco/source-tools-common/entity"
 	"github.com/galaco/vmf"
 )
 
@@ -17,10 +16,18 @@ type Vmf struct {
 	versionInfo  VersionInfo
 	visGroups    VisGroups
 	viewSettings ViewSettings
-	world        world.World
-	// entities     entity.List
-	cameras Cameras
-	cordon  Cordon
+	world        *world.World
+	// Keyvalues of the worldspawn such as skyname
+	worldProperties map[string]string
+	cameras         Cameras
+	cordon          Cordon
+
+	// Blocks that are not loaded are kept so that they are saved again.
+	// Groups and hidden solids in the world,
+	// unknown blocks after the entities and cordons at the end.
+	worldBlocks []world.Block
+	otherBlocks []world.Block
+	cordons     []world.Block
 }
 
 func (vmf *Vmf) VersionInfo() *VersionInfo {
@@ -36,12 +43,12 @@ func (vmf *Vmf) ViewSettings() *ViewSettings {
 }
 
 func (vmf *Vmf) Worldspawn() *world.World {
-	return &vmf.world
+	return vmf.world
 }
 
-// func (vmf *Vmf) Entities() *entity.List {
-// 	return &vmf.entities
-// }
+func (vmf *Vmf) Entities() []*Entity {
+	return vmf.world.Entities()
+}
 
 func (vmf *Vmf) Cameras() *Cameras {
 	return &vmf.cameras
@@ -70,6 +77,14 @@ func NewVersionInfo(version int, build int, mapRevision int, format int, isPrefa
 }
 
 type VisGroups struct {
+	Groups []VisGroup
+}
+
+type VisGroup struct {
+	Name     string
+	Id       int
+	Color    math32.Vector3
+	Children []VisGroup
 }
 
 type ViewSettings struct {
@@ -104,6 +119,10 @@ func NewCamera(position math32.Vector3, look math32.Vector3) *Camera {
 	}
 }
 
+// Entity is an entity from the entities of a vmf.
+// They are kept by the world so that they share its ids.
+type Entity = world.Entity
+
 type Cordon struct {
 	mins   math32.Vector3
 	maxs   math32.Vector3
@@ -113,14 +132,12 @@ type Cordon struct {
 func NewVmf(version *VersionInfo,
 	visgroups *VisGroups,
 	worldSpawn *world.World,
-	// entities *entity.List,
 	cameras *Cameras) *Vmf {
 	return &Vmf{
 		versionInfo: *version,
 		visGroups:   *visgroups,
-		world:       *worldSpawn,
-		// entities:    *entities,
-		cameras: *cameras,
+		world:       worldSpawn,
+		cameras:     *cameras,
 	}
 }
 
@@ -149,7 +166,12 @@ func LoadVmf(filepath string) (*Vmf, error) {
 	if err != nil || visGroups == nil {
 		return nil, err
 	}
-	worldspawn, err := loadWorld(&importable.World)
+	entities, err := loadEntities(&importable.Entities)
+	if err != nil {
+		return nil, err
+	}
+
+	worldspawn, err := loadWorld(&importable.World, entities)
 	if err != nil || worldspawn == nil {
 		return nil, err
 	}
@@ -159,11 +181,20 @@ func LoadVmf(filepath string) (*Vmf, error) {
 		return nil, err
 	}
 
-	// entities := loadEntities(&importable.Entities)
+	v := NewVmf(versionInfo, visGroups, worldspawn, cameras)
+	v.viewSettings = *loadViewSettings(&importable.ViewSettings)
+	v.worldProperties = loadProperties(&importable.World)
+	v.worldBlocks = loadBlocks(&importable.World, "solid")
+	v.otherBlocks = loadBlocks(&importable.Unclassified)
+
+	// Only one of these is in a vmf depending on the game
+	for _, cordon := range []*vmf.Node{&importable.Cordon, &importable.Cordons} {
+		if *cordon.GetKey() != "" {
+			v.cordons = append(v.cordons, loadBlock(cordon))
+		}
+	}
 
-	return NewVmf(versionInfo, visGroups, worldspawn,
-		// entities,
-		cameras), nil
+	return v, nil
 }
 
 // loadVersionInfo creates a VersionInfo model
@@ -199,12 +230,69 @@ func loadVersionInfo(root *vmf.Node) (*VersionInfo, error) {
 // loadVisgroups loads all visgroup information from the
 // visgroups block of a vmf
 func loadVisGroups(root *vmf.Node) (*VisGroups, error) {
-	return &VisGroups{}, nil
+	visGroups := &VisGroups{}
+
+	for _, node := range root.GetChildrenByKey("visgroups") {
+		visGroups.Groups = append(visGroups.Groups, loadVisGroupChildren(&node)...)
+	}
+
+	return visGroups, nil
 }
 
-func loadWorld(root *vmf.Node) (*world.World, error) {
-	solidNodes := root.GetChildrenByKey("solid")
+// loadVisGroupChildren loads the visgroups inside of node
+func loadVisGroupChildren(node *vmf.Node) []VisGroup {
+	var groups []VisGroup
+
+	for _, child := range node.GetChildrenByKey("visgroup") {
+		id, _ := strconv.ParseInt(child.GetProperty("visgroupid"), 10, 32)
+
+		var r, g, b float32
+		fmt.Sscanf(child.GetProperty("color"), "%f %f %f", &r, &g, &b)
+
+		groups = append(groups, VisGroup{
+			Name:     child.GetProperty("name"),
+			Id:       int(id),
+			Color:    math32.Vector3{r, g, b},
+			Children: loadVisGroupChildren(&child),
+		})
+	}
+
+	return groups
+}
+
+// loadViewSettings loads the viewsettings block
+// using the defaults of hammer for anything that is missing
+func loadViewSettings(root *vmf.Node) *ViewSettings {
+	setting := func(key string, value int) int {
+		if v, err := strconv.ParseInt(root.GetProperty(key), 10, 32); err == nil {
+			return int(v)
+		}
+
+		return value
+	}
+
+	return &ViewSettings{
+		SnapToGrid:      setting("bSnapToGrid", 1) != 0,
+		ShowGrid:        setting("bShowGrid", 1) != 0,
+		ShowLogicalGrid: setting("bShowLogicalGrid", 0) != 0,
+		GridSpacing:     setting("nGridSpacing", 64),
+		Show3DGrid:      setting("bShow3DGrid", 0) != 0,
+	}
+}
+
+func loadWorld(root *vmf.Node, entities []Entity) (*world.World, error) {
 	// worldSpawn := entity.FromVmfNode(root)
+	solids, err := loadSolids(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return world.NewWithEntities(solids, entities), nil
+}
+
+// loadSolids loads the solids that are children of root
+func loadSolids(root *vmf.Node) ([]world.Solid, error) {
+	solidNodes := root.GetChildrenByKey("solid")
 
 	solids := make([]world.Solid, len(solidNodes))
 	for idx, solidNode := range solidNodes {
@@ -215,11 +303,17 @@ func loadWorld(root *vmf.Node) (*world.World, error) {
 		solids[idx] = *solid
 	}
 
-	return world.New(solids), nil
+	return solids, nil
 }
 
 func loadEditor(solidNode *vmf.Node) *world.Editor {
-	e := solidNode.GetChildrenByKey("editor")[0]
+	editors := solidNode.GetChildrenByKey("editor")
+	if len(editors) == 0 {
+		// Pasted text doesnt always have one
+		return world.NewEditor(math32.Vector3{255, 255, 255}, true, true)
+	}
+
+	e := editors[0]
 
 	var x, y, z float32
 
@@ -233,7 +327,16 @@ func loadEditor(solidNode *vmf.Node) *world.Editor {
 	visGroup = visGroupInt == 1
 	visGroupAuto = visGroupAutoInt == 1
 
-	return world.NewEditor(math32.Vector3{x, y, z}, visGroup, visGroupAuto)
+	editor := world.NewEditor(math32.Vector3{x, y, z}, visGroup, visGroupAuto)
+
+	groupId, _ := strconv.ParseInt(e.GetProperty("groupid"), 10, 0)
+	editor.GroupId = int(groupId)
+
+	if visGroupId, err := strconv.ParseInt(e.GetProperty("visgroupid"), 10, 0); err == nil {
+		editor.VisgroupIds = append(editor.VisgroupIds, int(visGroupId))
+	}
+
+	return editor
 }
 
 // loadSolid takes a vmf node tree that represents a solid and turns
@@ -280,6 +383,11 @@ func loadSolid(node *vmf.Node) (*world.Solid, error) {
 		}
 
 		sides[idx] = *world.NewSide(int(id), plane, material, u, v, float32(rotation), float32(lmScale), smoothing)
+
+		if dispInfo := sideNode.GetChildrenByKey("dispinfo"); len(dispInfo) != 0 {
+			block := loadBlock(&dispInfo[0])
+			sides[idx].DispInfo = &block
+		}
 	}
 
 	editor := loadEditor(node)
@@ -287,12 +395,116 @@ func loadSolid(node *vmf.Node) (*world.Solid, error) {
 	return world.NewSolid(int(id), sides, editor), nil
 }
 
+// loadProperties returns the keyvalues of node
+// ignoring any child blocks
+func loadProperties(node *vmf.Node) map[string]string {
+	properties := map[string]string{}
+
+	for _, child := range *node.GetAllValues() {
+		property, ok := child.(vmf.Node)
+		if !ok {
+			continue
+		}
+
+		if value, ok := propertyValue(&property); ok {
+			properties[*property.GetKey()] = value
+		}
+	}
+
+	return properties
+}
+
+// loadBlock copies node and everything inside of it
+// so that it can be written back as it was loaded
+func loadBlock(node *vmf.Node) world.Block {
+	block := world.Block{Key: *node.GetKey()}
+
+	for _, child := range *node.GetAllValues() {
+		n, ok := child.(vmf.Node)
+		if !ok {
+			continue
+		}
+
+		if value, ok := propertyValue(&n); ok {
+			block.Properties = append(block.Properties, world.Property{Key: *n.GetKey(), Value: value})
+		} else {
+			block.Children = append(block.Children, loadBlock(&n))
+		}
+	}
+
+	return block
+}
+
+// loadBlocks copies the child blocks of node
+// apart from those with one of the keys in skip
+func loadBlocks(node *vmf.Node, skip ...string) []world.Block {
+	var blocks []world.Block
+
+	for _, child := range *node.GetAllValues() {
+		n, ok := child.(vmf.Node)
+		if !ok {
+			continue
+		}
+
+		_, skipped := propertyValue(&n)
+		for _, key := range skip {
+			skipped = skipped || *n.GetKey() == key
+		}
+
+		if !skipped {
+			blocks = append(blocks, loadBlock(&n))
+		}
+	}
+
+	return blocks
+}
+
+// propertyValue returns the value of node if it is a property
+// rather than a block. Properties only have a single string value.
+func propertyValue(node *vmf.Node) (string, bool) {
+	values := *node.GetAllValues()
+	if len(values) != 1 {
+		return "", false
+	}
+
+	value, ok := values[0].(string)
+
+	return value, ok
+}
+
 // loadEntities creates models from the entity data block
 // from a vmf
-func loadEntities(node *vmf.Node) *entity.List {
-	entities := entity.FromVmfNodeTree(*node)
+func loadEntities(node *vmf.Node) ([]Entity, error) {
+	entities := []Entity{}
+
+	for _, n := range *node.GetAllValues() {
+		entityNode, ok := n.(vmf.Node)
+		if !ok {
+			continue
+		}
+
+		id, _ := strconv.ParseInt(entityNode.GetProperty("id"), 10, 32)
+
+		e := Entity{
+			Id:         int(id),
+			Classname:  entityNode.GetProperty("classname"),
+			Properties: loadProperties(&entityNode),
+			Blocks:     loadBlocks(&entityNode, "solid"),
+		}
+
+		solidNodes := entityNode.GetChildrenByKey("solid")
+		for _, solidNode := range solidNodes {
+			solid, err := loadSolid(&solidNode)
+			if err != nil {
+				return nil, err
+			}
+			e.Solids = append(e.Solids, *solid)
+		}
+
+		entities = append(entities, e)
+	}
 
-	return &entities
+	return entities, nil
 }
 
 func NewVec3FromString(marshalled string) math32.Vector3 {
@@ -0,0 +1,354 @@
+package vmf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emily33901/forgery/core/world"
+	"github.com/g3n/engine/math32"
+	"github.com/galaco/vmf"
+)
+
+// Save writes vmf to filepath. It is written next to filepath
+// first so that a failed save does not destroy the old file.
+func Save(filepath string, vmf *Vmf) error {
+	temp := filepath + ".tmp"
+
+	file, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(file)
+	err = vmf.Write(out)
+
+	if err == nil {
+		err = out.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	return os.Rename(temp, filepath)
+}
+
+// Write writes this vmf as text. Blocks that forgery does not edit
+// such as entity connections, displacements and cordons
+// are written as they were loaded.
+func (vmf *Vmf) Write(out io.Writer) error {
+	w := &kvWriter{out: out}
+
+	v := &vmf.versionInfo
+	w.open("versioninfo")
+	w.property("editorversion", strconv.Itoa(v.EditorVersion))
+	w.property("editorbuild", strconv.Itoa(v.EditorBuild))
+	w.property("mapversion", strconv.Itoa(v.MapVersion))
+	w.property("formatversion", strconv.Itoa(v.FormatVersion))
+	w.property("prefab", formatBool(v.Prefab))
+	w.close()
+
+	w.open("visgroups")
+
+	for i := range vmf.visGroups.Groups {
+		writeVisGroup(w, &vmf.visGroups.Groups[i])
+	}
+
+	w.close()
+
+	vs := &vmf.viewSettings
+	w.open("viewsettings")
+	w.property("bSnapToGrid", formatBool(vs.SnapToGrid))
+	w.property("bShowGrid", formatBool(vs.ShowGrid))
+	w.property("bShowLogicalGrid", formatBool(vs.ShowLogicalGrid))
+	w.property("nGridSpacing", strconv.Itoa(vs.GridSpacing))
+	w.property("bShow3DGrid", formatBool(vs.Show3DGrid))
+	w.close()
+
+	properties := map[string]string{}
+	for k, v := range vmf.worldProperties {
+		properties[k] = v
+	}
+	properties["classname"] = "worldspawn"
+
+	w.open("world")
+	writeProperties(w, properties)
+
+	for _, s := range vmf.world.Solids() {
+		writeSolid(w, s)
+	}
+
+	writeBlocks(w, vmf.worldBlocks)
+
+	w.close()
+
+	for _, e := range vmf.world.Entities() {
+		writeEntity(w, e)
+	}
+
+	writeBlocks(w, vmf.otherBlocks)
+
+	w.open("cameras")
+	w.property("activecamera", strconv.Itoa(vmf.cameras.ActiveCamera))
+
+	for _, c := range vmf.cameras.CameraList {
+		w.open("camera")
+		w.property("position", "["+formatVec3(&c.Position)+"]")
+		w.property("look", "["+formatVec3(&c.Look)+"]")
+		w.close()
+	}
+
+	w.close()
+
+	writeBlocks(w, vmf.cordons)
+
+	return w.err
+}
+
+func writeVisGroup(w *kvWriter, g *VisGroup) {
+	w.open("visgroup")
+	w.property("name", g.Name)
+	w.property("visgroupid", strconv.Itoa(g.Id))
+	w.property("color", formatVec3(&g.Color))
+
+	for i := range g.Children {
+		writeVisGroup(w, &g.Children[i])
+	}
+
+	w.close()
+}
+
+// writeBlock writes b exactly as it was loaded
+func writeBlock(w *kvWriter, b *world.Block) {
+	w.open(b.Key)
+
+	for _, p := range b.Properties {
+		w.property(p.Key, p.Value)
+	}
+
+	writeBlocks(w, b.Children)
+
+	w.close()
+}
+
+func writeBlocks(w *kvWriter, blocks []world.Block) {
+	for i := range blocks {
+		writeBlock(w, &blocks[i])
+	}
+}
+
+// writeProperties writes keyvalues with id and classname first
+// like hammer does and the rest sorted so that saves are stable
+func writeProperties(w *kvWriter, properties map[string]string) {
+	keys := make([]string, 0, len(properties))
+
+	for k := range properties {
+		if k != "id" && k != "classname" {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range append([]string{"id", "classname"}, keys...) {
+		if v, ok := properties[k]; ok {
+			w.property(k, v)
+		}
+	}
+}
+
+func writeEntity(w *kvWriter, e *Entity) {
+	// Ids may have been repaired since the entity was loaded
+	properties := map[string]string{}
+	for k, v := range e.Properties {
+		properties[k] = v
+	}
+	properties["id"] = strconv.Itoa(e.Id)
+	properties["classname"] = e.Classname
+
+	w.open("entity")
+	writeProperties(w, properties)
+
+	// Hammer puts connections before solids and everything else after
+	for i := range e.Blocks {
+		if e.Blocks[i].Key == "connections" {
+			writeBlock(w, &e.Blocks[i])
+		}
+	}
+
+	for j := range e.Solids {
+		writeSolid(w, &e.Solids[j])
+	}
+
+	for i := range e.Blocks {
+		if e.Blocks[i].Key != "connections" {
+			writeBlock(w, &e.Blocks[i])
+		}
+	}
+
+	w.close()
+}
+
+// WriteClipboard writes solids as vmf text wrapped in a world block
+// followed by entities. This is what goes onto the clipboard
+// when solids and entities are copied.
+func WriteClipboard(out io.Writer, solids []world.Solid, entities []Entity) error {
+	w := &kvWriter{out: out}
+
+	w.open("world")
+	w.property("id", "1")
+	w.property("classname", "worldspawn")
+
+	for i := range solids {
+		writeSolid(w, &solids[i])
+	}
+
+	w.close()
+
+	for i := range entities {
+		writeEntity(w, &entities[i])
+	}
+
+	return w.err
+}
+
+// ReadClipboard reads solids and entities from vmf
+// text written by WriteClipboard or hammer
+func ReadClipboard(in io.Reader) ([]world.Solid, []Entity, error) {
+	reader := vmf.NewReader(in)
+	importable, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	solids, err := loadSolids(&importable.World)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entities, err := loadEntities(&importable.Entities)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(solids) == 0 && len(entities) == 0 {
+		return nil, nil, errors.New("no solids or entities found")
+	}
+
+	return solids, entities, nil
+}
+
+func writeSolid(w *kvWriter, s *world.Solid) {
+	w.open("solid")
+	w.property("id", strconv.Itoa(s.Id))
+
+	for _, side := range s.Sides {
+		p := side.Plane.Points
+
+		w.open("side")
+		w.property("id", strconv.Itoa(side.Id))
+		w.property("plane", fmt.Sprintf("(%s) (%s) (%s)", formatVec3d(p[0]), formatVec3d(p[1]), formatVec3d(p[2])))
+		w.property("material", side.Material)
+		w.property("uaxis", formatUVTransform(&side.UAxis))
+		w.property("vaxis", formatUVTransform(&side.VAxis))
+		w.property("rotation", formatFloat(side.Rotation))
+		w.property("lightmapscale", formatFloat(side.LightmapScale))
+		w.property("smoothing_groups", formatBool(side.SmoothingGroups))
+
+		if side.DispInfo != nil {
+			writeBlock(w, side.DispInfo)
+		}
+
+		w.close()
+	}
+
+	if e := s.Editor; e != nil {
+		w.open("editor")
+		w.property("color", formatVec3(&e.Color))
+
+		for _, id := range e.VisgroupIds {
+			w.property("visgroupid", strconv.Itoa(id))
+		}
+
+		if e.GroupId != 0 {
+			w.property("groupid", strconv.Itoa(e.GroupId))
+		}
+
+		w.property("visgroupshown", formatBool(e.VisgroupShown()))
+		w.property("visgroupautoshown", formatBool(e.VisgroupAutoShown()))
+		w.close()
+	}
+
+	w.close()
+}
+
+func formatFloat(f float32) string {
+	return strconv.FormatFloat(float64(f), 'f', -1, 32)
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
+
+func formatVec3(v *math32.Vector3) string {
+	return formatFloat(v.X) + " " + formatFloat(v.Y) + " " + formatFloat(v.Z)
+}
+
+func formatVec3d(v world.Vector3d) string {
+	return strconv.FormatFloat(v.X, 'f', -1, 64) + " " +
+		strconv.FormatFloat(v.Y, 'f', -1, 64) + " " +
+		strconv.FormatFloat(v.Z, 'f', -1, 64)
+}
+
+func formatUVTransform(t *world.UVTransform) string {
+	return fmt.Sprintf("[%s %s %s %s] %s",
+		formatFloat(t.Transform.X), formatFloat(t.Transform.Y),
+		formatFloat(t.Transform.Z), formatFloat(t.Transform.W),
+		formatFloat(t.Scale))
+}
+
+// kvWriter writes indented keyvalues text in the layout hammer uses
+type kvWriter struct {
+	out   io.Writer
+	depth int
+	err   error
+}
+
+func (w *kvWriter) line(s string) {
+	if w.err != nil {
+		return
+	}
+
+	_, w.err = io.WriteString(w.out, strings.Repeat("\t", w.depth)+s+"\n")
+}
+
+func (w *kvWriter) open(key string) {
+	w.line(key)
+	w.line("{")
+	w.depth++
+}
+
+func (w *kvWriter) close() {
+	w.depth--
+	w.line("}")
+}
+
+// property writes a keyvalue. Like hammer nothing is escaped.
+func (w *kvWriter) property(key, value string) {
+	w.line("\"" + key + "\" \"" + value + "\"")
+}
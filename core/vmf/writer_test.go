@@ -0,0 +1,116 @@
+package vmf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/emily33901/forgery/core/events"
+)
+
+func TestClipboardRoundTrip(t *testing.T) {
+	file, err := os.Open("testdata/stats.vmf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	solids, entities, err := ReadClipboard(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(solids) != 1 || len(entities) != 2 {
+		t.Fatalf("read %d solids and %d entities, want 1 and 2", len(solids), len(entities))
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteClipboard(buf, solids, entities); err != nil {
+		t.Fatal(err)
+	}
+
+	pastedSolids, pastedEntities, err := ReadClipboard(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(pastedSolids, solids) {
+		t.Errorf("solids changed from %+v to %+v", solids, pastedSolids)
+	}
+
+	if !reflect.DeepEqual(pastedEntities, entities) {
+		t.Errorf("entities changed from %+v to %+v", entities, pastedEntities)
+	}
+}
+
+func TestReadClipboardEmpty(t *testing.T) {
+	if _, _, err := ReadClipboard(bytes.NewBufferString("world\n{\n}\n")); err == nil {
+		t.Error("expected an error when there is nothing to paste")
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	events.Init()
+
+	original, err := os.ReadFile("testdata/roundtrip.vmf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := LoadVmf("testdata/roundtrip.vmf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Worldspawn().Close()
+
+	saved := filepath.Join(t.TempDir(), "roundtrip.vmf")
+	if err := Save(saved, v); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fixture is laid out the way forgery writes so nothing should change
+	if string(written) != string(original) {
+		t.Errorf("saved vmf differs from what was loaded:\n%s", written)
+	}
+
+	reloaded, err := LoadVmf(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Worldspawn().Close()
+
+	if !reflect.DeepEqual(reloaded.Visgroups(), v.Visgroups()) || len(v.Visgroups().Groups[0].Children) != 1 {
+		t.Errorf("visgroups changed from %+v to %+v", v.Visgroups(), reloaded.Visgroups())
+	}
+
+	if !reflect.DeepEqual(reloaded.ViewSettings(), v.ViewSettings()) || v.ViewSettings().GridSpacing != 16 {
+		t.Errorf("view settings changed from %+v to %+v", v.ViewSettings(), reloaded.ViewSettings())
+	}
+
+	if !reflect.DeepEqual(reloaded.Worldspawn().Solids(), v.Worldspawn().Solids()) {
+		t.Errorf("solids changed")
+	}
+
+	if v.Worldspawn().Solids()[0].Sides[0].DispInfo == nil {
+		t.Errorf("displacement was not loaded")
+	}
+
+	if !reflect.DeepEqual(reloaded.Entities(), v.Entities()) {
+		t.Errorf("entities changed from %+v to %+v", v.Entities(), reloaded.Entities())
+	}
+
+	if connections := v.Entities()[0].Blocks[0]; connections.Key != "connections" || len(connections.Properties) != 2 {
+		t.Errorf("connections were not loaded, got %+v", connections)
+	}
+
+	if !reflect.DeepEqual(reloaded.cordons, v.cordons) || len(v.cordons) != 1 {
+		t.Errorf("cordons changed from %+v to %+v", v.cordons, reloaded.cordons)
+	}
+}
@@ -0,0 +1,52 @@
+package world
+
+// Block is a block of vmf keyvalues that forgery does not edit
+// but keeps so that it is saved again as it was loaded
+type Block struct {
+	Key        string
+	Properties []Property
+	Children   []Block
+}
+
+// Property is a keyvalue of a Block. Keys can repeat,
+// such as the outputs in the connections of an entity.
+type Property struct {
+	Key   string
+	Value string
+}
+
+// Property returns the first value of key or "" if there is none
+func (b *Block) Property(key string) string {
+	for _, p := range b.Properties {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+
+	return ""
+}
+
+// SetProperty changes the first value of key or adds it if there is none
+func (b *Block) SetProperty(key, value string) {
+	for i := range b.Properties {
+		if b.Properties[i].Key == key {
+			b.Properties[i].Value = value
+			return
+		}
+	}
+
+	b.Properties = append(b.Properties, Property{key, value})
+}
+
+// cloneBlock deep copies b so that later changes to b do not affect it
+func cloneBlock(b *Block) Block {
+	c := *b
+	c.Properties = append([]Property(nil), b.Properties...)
+
+	c.Children = nil
+	for i := range b.Children {
+		c.Children = append(c.Children, cloneBlock(&b.Children[i]))
+	}
+
+	return c
+}
@@ -0,0 +1,263 @@
+package world
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/g3n/engine/math32"
+)
+
+// FaceData is the cpu side geometry of a single face ready to be uploaded.
+// Positions and the normal are already in scene space.
+type FaceData struct {
+	SideId   int
+	Material string
+
+	Positions []math32.Vector3
+	Normal    math32.Vector3
+	Indices   []uint32
+
+	// UVs in texels, these are divided by the texture
+	// size when uploading once the material is known
+	UVs []float64
+}
+
+// SolidData is the cpu side geometry of every face of a solid
+type SolidData struct {
+	Id    int
+	Color math32.Color
+	Faces []FaceData
+
+	version int
+}
+
+// BuildError is a solid that panicked while it was being built
+type BuildError struct {
+	SolidId int
+	Err     error
+
+	version int
+}
+
+func (e *BuildError) Error() string {
+	return e.Err.Error()
+}
+
+// NewFaceData does all of the work to turn a winding into a face
+// that does not need the gpu or the material
+func NewFaceData(w *Winding, side *Side) FaceData {
+	face := FaceData{
+		SideId:   side.Id,
+		Material: side.Material,
+	}
+
+	for _, v := range w.Vertices() {
+		face.Positions = append(face.Positions, SwapYZ(*v))
+	}
+
+	// Triangle fan
+	for j := 1; j+1 < len(face.Positions); j++ {
+		face.Indices = append(face.Indices, 0, uint32(j), uint32(j+1))
+	}
+
+	// Calculate the normal by picking 2 points and crossing them
+	a := face.Positions[0]
+	b := face.Positions[1]
+	c := face.Positions[2]
+	face.Normal = *b.Sub(&a).Cross(c.Sub(&a))
+
+	// Work out uvs in double precision so that
+	// they dont drift far from the origin
+	u := &side.UAxis
+	v := &side.VAxis
+
+	for _, vertex := range w.Points {
+		cu := ((float64(u.Transform.X) * vertex.X) +
+			(float64(u.Transform.Y) * vertex.Y) +
+			(float64(u.Transform.Z) * vertex.Z)) / float64(u.Scale)
+
+		cv := ((float64(v.Transform.X) * vertex.X) +
+			(float64(v.Transform.Y) * vertex.Y) +
+			(float64(v.Transform.Z) * vertex.Z)) / float64(v.Scale)
+
+		face.UVs = append(face.UVs, cu, cv)
+	}
+
+	return face
+}
+
+// NewSolidData generates the faces of s
+func NewSolidData(s *Solid) *SolidData {
+	data := &SolidData{
+		Id:    s.Id,
+		Color: s.EditorColor(),
+	}
+
+	for i, winding := range s.Windings() {
+		if winding == nil {
+			continue
+		}
+
+		data.Faces = append(data.Faces, NewFaceData(winding, &s.Sides[i]))
+	}
+
+	return data
+}
+
+type buildJob struct {
+	solid      Solid
+	version    int
+	generation int
+}
+
+// Builder is a pool of goroutines that generate SolidData
+// so that the render thread only has to upload it
+type Builder struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	queue   []buildJob
+	results []*SolidData
+	failed  []*BuildError
+	busy    int
+
+	// Bumped on cancel so that in flight jobs are thrown away
+	generation int
+	stopped    bool
+}
+
+// NewBuilder starts a builder with workers goroutines.
+// If workers is 0 one is started per cpu.
+func NewBuilder(workers int) *Builder {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	b := &Builder{}
+	b.cond = sync.NewCond(&b.mu)
+
+	for i := 0; i < workers; i++ {
+		go b.work()
+	}
+
+	return b
+}
+
+func (b *Builder) work() {
+	for {
+		b.mu.Lock()
+		for len(b.queue) == 0 && !b.stopped {
+			b.cond.Wait()
+		}
+
+		if b.stopped {
+			b.mu.Unlock()
+			return
+		}
+
+		job := b.queue[0]
+		b.queue = b.queue[1:]
+		b.busy++
+		b.mu.Unlock()
+
+		data, err := build(&job)
+
+		b.mu.Lock()
+		b.busy--
+		if job.generation == b.generation {
+			if err != nil {
+				b.failed = append(b.failed, &BuildError{job.solid.Id, err, job.version})
+			} else {
+				b.results = append(b.results, data)
+			}
+		}
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	}
+}
+
+// build builds the solid of job. A panic is turned into an error
+// so that one broken solid does not kill forgery from a worker.
+func build(job *buildJob) (data *SolidData, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("building solid %d: %v\n%s", job.solid.Id, r, debug.Stack())
+		}
+	}()
+
+	data = NewSolidData(&job.solid)
+	data.version = job.version
+
+	return data, nil
+}
+
+// Queue queues s to be built. The builder keeps its own copy of
+// the solid so the caller is free to keep changing it.
+func (b *Builder) Queue(s *Solid, version int) {
+	job := buildJob{
+		solid:   *s,
+		version: version,
+	}
+
+	job.solid.Sides = append([]Side(nil), s.Sides...)
+
+	b.mu.Lock()
+	job.generation = b.generation
+	b.queue = append(b.queue, job)
+	// Wait shares the cond so Signal could wake it instead of a worker
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// Results takes every solid that has finished building
+// and every solid that panicked while it was being built
+func (b *Builder) Results() ([]*SolidData, []*BuildError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	results, failed := b.results, b.failed
+	b.results, b.failed = nil, nil
+
+	return results, failed
+}
+
+// Outstanding returns how many solids are queued or being built
+func (b *Builder) Outstanding() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.queue) + b.busy
+}
+
+// Wait blocks until every queued solid has been built
+func (b *Builder) Wait() {
+	b.mu.Lock()
+	for (len(b.queue) != 0 || b.busy != 0) && !b.stopped {
+		b.cond.Wait()
+	}
+	b.mu.Unlock()
+}
+
+// Cancel throws away everything that is queued, being built
+// or waiting to be collected
+func (b *Builder) Cancel() {
+	b.mu.Lock()
+	b.generation++
+	b.queue = nil
+	b.results = nil
+	b.failed = nil
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// Stop cancels all work and stops the workers
+func (b *Builder) Stop() {
+	b.Cancel()
+
+	b.mu.Lock()
+	b.stopped = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
@@ -0,0 +1,412 @@
+package world
+
+import (
+	"math"
+	"sort"
+
+	"github.com/g3n/engine/math32"
+)
+
+// BVH is a bounding volume hierarchy over the bounds of solids.
+// It lets ray, box and frustum queries skip most of the world
+// instead of testing every face.
+type BVH struct {
+	root *bvhNode
+
+	// leaves by solid id so that single solids can be
+	// updated without rebuilding the whole tree
+	leaves map[int]*bvhNode
+}
+
+type bvhNode struct {
+	bounds math32.Box3
+	parent *bvhNode
+	left   *bvhNode
+	right  *bvhNode
+
+	// Only set on leaves
+	solidId int
+	sides   []Side
+}
+
+func (n *bvhNode) isLeaf() bool {
+	return n.left == nil
+}
+
+func (n *bvhNode) refit() {
+	n.bounds = n.left.bounds
+	n.bounds.Union(&n.right.bounds)
+}
+
+// RayHit describes the closest face that a ray hit
+type RayHit struct {
+	SolidId  int
+	SideId   int
+	Point    math32.Vector3
+	Distance float32
+}
+
+// NewBVH builds a BVH over solids.
+// Solid ids are expected to be unique, World makes sure of this.
+func NewBVH(solids []Solid) *BVH {
+	b := &BVH{
+		leaves: map[int]*bvhNode{},
+	}
+
+	leaves := make([]*bvhNode, 0, len(solids))
+
+	for i := range solids {
+		leaf := newBVHLeaf(&solids[i])
+		if leaf == nil {
+			continue
+		}
+
+		b.leaves[leaf.solidId] = leaf
+		leaves = append(leaves, leaf)
+	}
+
+	b.root = buildBVH(leaves)
+
+	return b
+}
+
+func newBVHLeaf(s *Solid) *bvhNode {
+	leaf := &bvhNode{
+		solidId: s.Id,
+		sides:   s.Sides,
+	}
+
+	leaf.bounds = s.Bounds()
+
+	if leaf.bounds.Max.X < leaf.bounds.Min.X {
+		// No faces so nothing to hit
+		return nil
+	}
+
+	return leaf
+}
+
+func newBVHBranch(left, right *bvhNode) *bvhNode {
+	n := &bvhNode{
+		left:  left,
+		right: right,
+	}
+
+	left.parent = n
+	right.parent = n
+	n.refit()
+
+	return n
+}
+
+// buildBVH builds a tree top down by splitting leaves
+// in half along the longest axis of their centres
+func buildBVH(leaves []*bvhNode) *bvhNode {
+	switch len(leaves) {
+	case 0:
+		return nil
+	case 1:
+		leaves[0].parent = nil
+		return leaves[0]
+	}
+
+	centres := math32.Box3{}
+	centres.MakeEmpty()
+
+	for _, l := range leaves {
+		c := boxCentre(&l.bounds)
+		centres.ExpandByPoint(&c)
+	}
+
+	size := centres.Max.Clone().Sub(&centres.Min)
+
+	axis := 0
+	for i := 1; i < 3; i++ {
+		if size.Component(i) > size.Component(axis) {
+			axis = i
+		}
+	}
+
+	sort.Slice(leaves, func(i, j int) bool {
+		a := boxCentre(&leaves[i].bounds)
+		b := boxCentre(&leaves[j].bounds)
+		return a.Component(axis) < b.Component(axis)
+	})
+
+	mid := len(leaves) / 2
+
+	return newBVHBranch(buildBVH(leaves[:mid]), buildBVH(leaves[mid:]))
+}
+
+// Update rebuilds the leaf for s after its sides have changed
+// or inserts it if it is not already in the tree
+func (b *BVH) Update(s *Solid) {
+	b.Remove(s.Id)
+
+	leaf := newBVHLeaf(s)
+	if leaf == nil {
+		return
+	}
+
+	b.insert(leaf)
+}
+
+// Remove removes the solid with id from the tree
+func (b *BVH) Remove(id int) {
+	leaf, ok := b.leaves[id]
+	if !ok {
+		return
+	}
+
+	delete(b.leaves, id)
+
+	parent := leaf.parent
+	if parent == nil {
+		b.root = nil
+		return
+	}
+
+	// Replace the parent with the leafs sibling
+	sibling := parent.left
+	if sibling == leaf {
+		sibling = parent.right
+	}
+
+	grandParent := parent.parent
+	sibling.parent = grandParent
+
+	if grandParent == nil {
+		b.root = sibling
+		return
+	}
+
+	if grandParent.left == parent {
+		grandParent.left = sibling
+	} else {
+		grandParent.right = sibling
+	}
+
+	refitUpwards(grandParent)
+}
+
+func (b *BVH) insert(leaf *bvhNode) {
+	b.leaves[leaf.solidId] = leaf
+
+	if b.root == nil {
+		leaf.parent = nil
+		b.root = leaf
+		return
+	}
+
+	// Walk down the tree picking whichever child grows the least
+	sibling := b.root
+	for !sibling.isLeaf() {
+		l := enlargement(&sibling.left.bounds, &leaf.bounds)
+		r := enlargement(&sibling.right.bounds, &leaf.bounds)
+
+		if l < r {
+			sibling = sibling.left
+		} else {
+			sibling = sibling.right
+		}
+	}
+
+	oldParent := sibling.parent
+	branch := newBVHBranch(sibling, leaf)
+	branch.parent = oldParent
+
+	if oldParent == nil {
+		b.root = branch
+		return
+	}
+
+	if oldParent.left == sibling {
+		oldParent.left = branch
+	} else {
+		oldParent.right = branch
+	}
+
+	refitUpwards(oldParent)
+}
+
+func refitUpwards(n *bvhNode) {
+	for ; n != nil; n = n.parent {
+		n.refit()
+	}
+}
+
+// Raycast returns the closest face that the ray enters through
+func (b *BVH) Raycast(origin, direction math32.Vector3) (RayHit, bool) {
+	hit := RayHit{
+		Distance: float32(math.Inf(1)),
+	}
+	found := false
+
+	if b.root == nil {
+		return hit, false
+	}
+
+	direction.Normalize()
+	invDirection := math32.Vector3{1 / direction.X, 1 / direction.Y, 1 / direction.Z}
+
+	stack := []*bvhNode{b.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		near, ok := rayBoxDistance(&origin, &invDirection, &n.bounds)
+		if !ok || near > hit.Distance {
+			continue
+		}
+
+		if !n.isLeaf() {
+			stack = append(stack, n.left, n.right)
+			continue
+		}
+
+		intersection, ok := intersectRaySides(n.sides, &origin, &direction)
+
+		// Solids that the ray starts inside of cannot be hit
+		if !ok || intersection.Enter.Distance < 0 || intersection.Enter.Distance >= hit.Distance {
+			continue
+		}
+
+		found = true
+		hit.SolidId = n.solidId
+		hit.SideId = intersection.Enter.SideId
+		hit.Distance = intersection.Enter.Distance
+		VectorMAInline(&origin, &direction, &hit.Point, hit.Distance)
+	}
+
+	return hit, found
+}
+
+// QueryBox returns the ids of all solids whose bounds overlap box
+func (b *BVH) QueryBox(box math32.Box3) []int {
+	return b.query(func(bounds *math32.Box3) bool {
+		return boxesOverlap(bounds, &box)
+	})
+}
+
+// QueryFrustum returns the ids of all solids whose bounds are at least
+// partially inside of planes. Like solid sides the plane normals
+// should point inwards.
+func (b *BVH) QueryFrustum(planes []Plane) []int {
+	return b.query(func(bounds *math32.Box3) bool {
+		for i := range planes {
+			if boxOutsidePlane(bounds, &planes[i]) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+func (b *BVH) query(overlaps func(bounds *math32.Box3) bool) []int {
+	results := []int{}
+
+	if b.root == nil {
+		return results
+	}
+
+	stack := []*bvhNode{b.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if !overlaps(&n.bounds) {
+			continue
+		}
+
+		if n.isLeaf() {
+			results = append(results, n.solidId)
+			continue
+		}
+
+		stack = append(stack, n.left, n.right)
+	}
+
+	return results
+}
+
+// rayBoxDistance returns the distance along the ray to box
+// using the slab method
+func rayBoxDistance(origin, invDirection *math32.Vector3, box *math32.Box3) (float32, bool) {
+	near := float32(0)
+	far := float32(math.Inf(1))
+
+	for i := 0; i < 3; i++ {
+		o := origin.Component(i)
+		inv := invDirection.Component(i)
+
+		t1 := (box.Min.Component(i) - o) * inv
+		t2 := (box.Max.Component(i) - o) * inv
+
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+
+		if t1 > near {
+			near = t1
+		}
+
+		if t2 < far {
+			far = t2
+		}
+
+		if near > far {
+			return 0, false
+		}
+	}
+
+	return near, true
+}
+
+func boxesOverlap(a, b *math32.Box3) bool {
+	return a.Min.X <= b.Max.X && a.Max.X >= b.Min.X &&
+		a.Min.Y <= b.Max.Y && a.Max.Y >= b.Min.Y &&
+		a.Min.Z <= b.Max.Z && a.Max.Z >= b.Min.Z
+}
+
+// boxOutsidePlane checks whether box is entirely behind p
+func boxOutsidePlane(box *math32.Box3, p *Plane) bool {
+	// Find the corner furthest along the normal
+	corner := box.Min
+
+	if p.Normal.X > 0 {
+		corner.X = box.Max.X
+	}
+
+	if p.Normal.Y > 0 {
+		corner.Y = box.Max.Y
+	}
+
+	if p.Normal.Z > 0 {
+		corner.Z = box.Max.Z
+	}
+
+	return corner.Dot(&p.Normal)-p.Dist < 0
+}
+
+func boxCentre(box *math32.Box3) math32.Vector3 {
+	return math32.Vector3{
+		(box.Min.X + box.Max.X) * 0.5,
+		(box.Min.Y + box.Max.Y) * 0.5,
+		(box.Min.Z + box.Max.Z) * 0.5,
+	}
+}
+
+func boxSurfaceArea(box *math32.Box3) float32 {
+	d := box.Max.Clone().Sub(&box.Min)
+	return 2 * (d.X*d.Y + d.Y*d.Z + d.Z*d.X)
+}
+
+// enlargement is how much the surface area of a grows
+// if it is expanded to contain b
+func enlargement(a, b *math32.Box3) float32 {
+	union := *a
+	union.Union(b)
+
+	return boxSurfaceArea(&union) - boxSurfaceArea(a)
+}
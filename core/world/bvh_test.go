@@ -0,0 +1,208 @@
+package world
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/g3n/engine/math32"
+)
+
+func randomCube(r *rand.Rand, id int) Solid {
+	mins := Vector3d{
+		float64(r.Intn(4096) - 2048),
+		float64(r.Intn(4096) - 2048),
+		float64(r.Intn(4096) - 2048),
+	}
+
+	maxs := mins.Add(Vector3d{
+		float64(16 + r.Intn(240)),
+		float64(16 + r.Intn(240)),
+		float64(16 + r.Intn(240)),
+	})
+
+	return cube(id, id*6, mins, maxs)
+}
+
+func randomDirection(r *rand.Rand) math32.Vector3 {
+	d := math32.Vector3{r.Float32()*2 - 1, r.Float32()*2 - 1, r.Float32()*2 - 1}
+	return *d.Normalize()
+}
+
+// bruteRaycast tests the ray against every solid
+func bruteRaycast(solids map[int]*Solid, origin, direction math32.Vector3) (RayHit, bool) {
+	hit := RayHit{Distance: float32(math.Inf(1))}
+	found := false
+
+	direction.Normalize()
+
+	for id, s := range solids {
+		intersection, ok := intersectRaySides(s.Sides, &origin, &direction)
+		if !ok || intersection.Enter.Distance < 0 || intersection.Enter.Distance >= hit.Distance {
+			continue
+		}
+
+		found = true
+		hit.SolidId = id
+		hit.SideId = intersection.Enter.SideId
+		hit.Distance = intersection.Enter.Distance
+	}
+
+	return hit, found
+}
+
+// bruteQuery returns the ids of every solid whose bounds overlaps
+func bruteQuery(solids map[int]*Solid, overlaps func(bounds *math32.Box3) bool) []int {
+	results := []int{}
+
+	for id, s := range solids {
+		bounds := s.Bounds()
+		if overlaps(&bounds) {
+			results = append(results, id)
+		}
+	}
+
+	sort.Ints(results)
+
+	return results
+}
+
+func sameIds(a, b []int) bool {
+	a = append([]int(nil), a...)
+	sort.Ints(a)
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkBVH compares every kind of query on b with testing solids one by one
+func checkBVH(t *testing.T, r *rand.Rand, b *BVH, solids map[int]*Solid) {
+	t.Helper()
+
+	for i := 0; i < 200; i++ {
+		origin := math32.Vector3{
+			float32(r.Intn(6144) - 3072),
+			float32(r.Intn(6144) - 3072),
+			float32(r.Intn(6144) - 3072),
+		}
+		direction := randomDirection(r)
+
+		// Aim half of the rays at a solid so that most of them hit something
+		if s, ok := solids[1+r.Intn(300)]; ok && i%2 == 0 {
+			bounds := s.Bounds()
+			centre := boxCentre(&bounds)
+			direction = *centre.Sub(&origin).Normalize()
+		}
+
+		got, gotOk := b.Raycast(origin, direction)
+		want, wantOk := bruteRaycast(solids, origin, direction)
+
+		if gotOk != wantOk || got.SolidId != want.SolidId || got.SideId != want.SideId || got.Distance != want.Distance {
+			t.Errorf("ray %v %v hit %v %+v, want %v %+v", origin, direction, gotOk, got, wantOk, want)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		box := math32.Box3{}
+		box.MakeEmpty()
+
+		for j := 0; j < 2; j++ {
+			p := math32.Vector3{
+				float32(r.Intn(4096) - 2048),
+				float32(r.Intn(4096) - 2048),
+				float32(r.Intn(4096) - 2048),
+			}
+			box.ExpandByPoint(&p)
+		}
+
+		got := b.QueryBox(box)
+		want := bruteQuery(solids, func(bounds *math32.Box3) bool {
+			return boxesOverlap(bounds, &box)
+		})
+
+		if !sameIds(got, want) {
+			t.Errorf("box %v found %v, want %v", box, got, want)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		planes := make([]Plane, 4)
+		for j := range planes {
+			planes[j].Normal = randomDirection(r)
+			planes[j].Dist = float32(r.Intn(2048) - 1024)
+		}
+
+		got := b.QueryFrustum(planes)
+		want := bruteQuery(solids, func(bounds *math32.Box3) bool {
+			for j := range planes {
+				if boxOutsidePlane(bounds, &planes[j]) {
+					return false
+				}
+			}
+
+			return true
+		})
+
+		if !sameIds(got, want) {
+			t.Errorf("frustum %v found %v, want %v", planes, got, want)
+		}
+	}
+}
+
+func TestBVHMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	list := []Solid{}
+	for i := 1; i <= 300; i++ {
+		list = append(list, randomCube(r, i))
+	}
+
+	b := NewBVH(list)
+
+	solids := map[int]*Solid{}
+	for i := range list {
+		solids[list[i].Id] = &list[i]
+	}
+
+	checkBVH(t, r, b, solids)
+
+	// Move a third of the solids somewhere else
+	for id := 1; id <= 300; id += 3 {
+		moved := randomCube(r, id)
+		solids[id] = &moved
+		b.Update(&moved)
+	}
+
+	checkBVH(t, r, b, solids)
+
+	// Then remove another third
+	for id := 2; id <= 300; id += 3 {
+		delete(solids, id)
+		b.Remove(id)
+	}
+
+	checkBVH(t, r, b, solids)
+
+	// Removing everything leaves nothing to find
+	for id := range solids {
+		b.Remove(id)
+	}
+
+	if _, ok := b.Raycast(math32.Vector3{}, math32.Vector3{1, 0, 0}); ok {
+		t.Error("empty tree was hit")
+	}
+
+	if found := b.QueryBox(math32.Box3{Min: math32.Vector3{-4096, -4096, -4096}, Max: math32.Vector3{4096, 4096, 4096}}); len(found) != 0 {
+		t.Errorf("empty tree found %v", found)
+	}
+}
@@ -0,0 +1,276 @@
+package world
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/g3n/engine/math32"
+)
+
+// Entity is an entity from the entities of a vmf.
+// Brush entities keep their solids here rather than in the world.
+type Entity struct {
+	Id         int
+	Classname  string
+	Properties map[string]string
+	Solids     []Solid
+
+	// Child blocks that are not solids such as connections and editor
+	Blocks []Block
+}
+
+// Entity returns the entity with id or nil if there is none
+func (w *World) Entity(id int) *Entity {
+	if i, ok := w.entitiesById[id]; ok {
+		return &w.entities[i]
+	}
+
+	return nil
+}
+
+// Entities returns every entity in this world. The pointers are only valid
+// until entities are next added or removed.
+func (w *World) Entities() []*Entity {
+	ret := make([]*Entity, len(w.entities))
+
+	for i := range w.entities {
+		ret[i] = &w.entities[i]
+	}
+
+	return ret
+}
+
+// Origin returns the origin property of e
+// and whether it has one (brush entities often don't)
+func (e *Entity) Origin() (Vector3d, bool) {
+	origin := Vector3d{}
+
+	n, _ := fmt.Sscanf(e.Properties["origin"], "%f %f %f", &origin.X, &origin.Y, &origin.Z)
+
+	return origin, n == 3
+}
+
+// SetOrigin changes the origin property of e
+func (e *Entity) SetOrigin(origin Vector3d) {
+	if e.Properties == nil {
+		e.Properties = map[string]string{}
+	}
+
+	e.Properties["origin"] = strconv.FormatFloat(origin.X, 'f', -1, 64) + " " +
+		strconv.FormatFloat(origin.Y, 'f', -1, 64) + " " +
+		strconv.FormatFloat(origin.Z, 'f', -1, 64)
+}
+
+// cloneEntity deep copies e so that later changes to e do not affect it
+func cloneEntity(e *Entity) Entity {
+	c := *e
+
+	c.Properties = make(map[string]string, len(e.Properties))
+	for k, v := range e.Properties {
+		c.Properties[k] = v
+	}
+
+	c.Solids = make([]Solid, len(e.Solids))
+	for i := range e.Solids {
+		c.Solids[i] = cloneSolid(&e.Solids[i])
+	}
+
+	c.Blocks = nil
+	for i := range e.Blocks {
+		c.Blocks = append(c.Blocks, cloneBlock(&e.Blocks[i]))
+	}
+
+	return c
+}
+
+// transformEntity moves the origin and solids of e by m.
+// Angles and texture axes are left alone.
+func transformEntity(e *Entity, m *math32.Matrix4) {
+	if origin, ok := e.Origin(); ok {
+		e.SetOrigin(origin.ApplyMatrix4(m))
+	}
+
+	for i := range e.Solids {
+		transformPlanes(&e.Solids[i], m)
+	}
+}
+
+type createEntityCommand struct {
+	entity Entity
+}
+
+func (c *createEntityCommand) Do(w *World) {
+	w.insertEntity(cloneEntity(&c.entity))
+}
+
+func (c *createEntityCommand) Undo(w *World) {
+	w.removeEntityById(c.entity.Id)
+}
+
+func (c *createEntityCommand) Solids() []int {
+	return nil
+}
+
+type deleteEntityCommand struct {
+	entity Entity
+}
+
+func (c *deleteEntityCommand) Do(w *World) {
+	w.removeEntityById(c.entity.Id)
+}
+
+func (c *deleteEntityCommand) Undo(w *World) {
+	w.insertEntity(cloneEntity(&c.entity))
+}
+
+func (c *deleteEntityCommand) Solids() []int {
+	return nil
+}
+
+// entityPropertyCommand swaps one keyvalue of an entity between two values.
+// A nil value means the key is not set.
+type entityPropertyCommand struct {
+	entity int
+	key    string
+	before *string
+	after  *string
+}
+
+func (c *entityPropertyCommand) Do(w *World) {
+	c.apply(w, c.after)
+}
+
+func (c *entityPropertyCommand) Undo(w *World) {
+	c.apply(w, c.before)
+}
+
+func (c *entityPropertyCommand) apply(w *World, value *string) {
+	e := w.Entity(c.entity)
+	if e == nil {
+		return
+	}
+
+	if value == nil {
+		delete(e.Properties, c.key)
+		return
+	}
+
+	if e.Properties == nil {
+		e.Properties = map[string]string{}
+	}
+
+	e.Properties[c.key] = *value
+
+	if c.key == "classname" {
+		e.Classname = *value
+	}
+}
+
+func (c *entityPropertyCommand) Solids() []int {
+	return nil
+}
+
+// SetEntityProperty sets the keyvalue key of the entity with id to value
+// as an undoable action
+func (w *World) SetEntityProperty(id int, key, value string) {
+	w.changeEntityProperty("Set "+key, id, key, &value)
+}
+
+// RemoveEntityProperty removes the keyvalue key from the entity with id
+// as an undoable action
+func (w *World) RemoveEntityProperty(id int, key string) {
+	w.changeEntityProperty("Remove "+key, id, key, nil)
+}
+
+func (w *World) changeEntityProperty(name string, id int, key string, value *string) {
+	e := w.Entity(id)
+	if e == nil {
+		return
+	}
+
+	var before *string
+	if old, ok := e.Properties[key]; ok {
+		before = &old
+	}
+
+	if (before == nil && value == nil) || (before != nil && value != nil && *before == *value) {
+		// Nothing would change
+		return
+	}
+
+	w.Execute(name, &entityPropertyCommand{id, key, before, value})
+}
+
+// CreateEntity adds e to the world as an undoable action.
+// e is given new ids if it does not have any or they are already used.
+func (w *World) CreateEntity(e Entity) int {
+	e = cloneEntity(&e)
+
+	used := func(id int) bool {
+		return id <= 0 || w.Entity(id) != nil || w.Solid(id) != nil
+	}
+
+	needsIds := used(e.Id)
+	for _, s := range e.Solids {
+		if used(s.Id) {
+			needsIds = true
+		}
+
+		for _, side := range s.Sides {
+			if other, _ := w.Side(side.Id); side.Id <= 0 || other != nil {
+				needsIds = true
+			}
+		}
+	}
+
+	if needsIds {
+		w.AssignEntityIds(&e)
+	}
+
+	w.Execute("Create entity", &createEntityCommand{e})
+
+	return e.Id
+}
+
+// DeleteEntity removes the entity with id as an undoable action
+func (w *World) DeleteEntity(id int) {
+	e := w.Entity(id)
+	if e == nil {
+		return
+	}
+
+	w.Execute("Delete entity", &deleteEntityCommand{cloneEntity(e)})
+}
+
+// AssignEntityIds gives e, its solids and all of their sides new ids
+func (w *World) AssignEntityIds(e *Entity) {
+	e.Id = w.ids.NewObjectId()
+
+	for i := range e.Solids {
+		w.AssignIds(&e.Solids[i])
+	}
+}
+
+func (w *World) insertEntity(e Entity) {
+	w.ids.ReserveObjectId(e.Id)
+	for _, s := range e.Solids {
+		w.ids.ReserveObjectId(s.Id)
+
+		for _, side := range s.Sides {
+			w.ids.ReserveSideId(side.Id)
+		}
+	}
+
+	w.entities = append(w.entities, e)
+	w.entitiesById[e.Id] = len(w.entities) - 1
+}
+
+func (w *World) removeEntityById(id int) {
+	i, ok := w.entitiesById[id]
+	if !ok {
+		return
+	}
+
+	w.entities = append(w.entities[:i], w.entities[i+1:]...)
+	w.reindex()
+}
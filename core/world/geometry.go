@@ -12,6 +12,121 @@ type Solid struct {
 	Editor *Editor
 }
 
+// Windings clips every side of this solid against all of the others.
+// The returned slice lines up with Sides and holds nil for any side
+// that does not produce a face.
+func (s *Solid) Windings() []*Winding {
+	// https://github.com/emily33901/HammerFromScratch/blob/a0f669718a70632138545fd1a5a493b8299221a0/hammer/mapsolid.cpp#L788
+
+	usePlane := make([]bool, len(s.Sides))
+
+	for i, side := range s.Sides {
+		if side.Plane.Normal.LengthSq() == 0 {
+			// Not a valid plane
+			usePlane[i] = false
+			continue
+		}
+
+		usePlane[i] = true
+
+		// Check this plane isnt identical to another plane
+		for j, side2 := range s.Sides {
+			if i == j {
+				break
+			}
+
+			if side.Plane.Normal.Dot(&side2.Plane.Normal) > 0.999 && math32.Abs(side.Plane.Dist-side2.Plane.Dist) < 0.1 {
+				usePlane[j] = false
+			}
+		}
+	}
+
+	// Now that we have all of the faces and we know which ones to use
+	// its time to clip all of them to get the points
+	planes := make([]PlaneD, len(s.Sides))
+	for i := range s.Sides {
+		planes[i] = s.Sides[i].Plane.Precise()
+	}
+
+	windings := windingsOfPlanes(planes, usePlane)
+
+	for i := range windings {
+		if usePlane[i] && windings[i] == nil {
+			fmt.Println("Empty winding")
+		}
+	}
+
+	return windings
+}
+
+// windingsOfPlanes clips a winding on each plane that is used against every
+// other plane. Planes that are not used or end up with nothing left are nil.
+func windingsOfPlanes(planes []PlaneD, usePlane []bool) []*Winding {
+	windings := make([]*Winding, len(planes))
+
+	for i := range planes {
+		if usePlane[i] == false {
+			// we are not using this plane
+			continue
+		}
+
+		winding := CreateWindingFromPlane(&planes[i])
+
+		for j := range planes {
+			if j != i && len(winding.Points) > 0 {
+				winding.Clip(&planes[j])
+			}
+		}
+
+		if len(winding.Points) == 0 {
+			continue
+		}
+
+		windings[i] = winding
+	}
+
+	return windings
+}
+
+// EditorColor returns the colour that this solid is drawn with
+// when it is not textured
+func (s *Solid) EditorColor() math32.Color {
+	if s.Editor == nil {
+		return math32.Color{1, 1, 1}
+	}
+
+	return math32.Color{
+		s.Editor.Color.X / 255,
+		s.Editor.Color.Y / 255,
+		s.Editor.Color.Z / 255,
+	}
+}
+
+// Bounds returns the axis aligned box containing every face of this solid
+func (s *Solid) Bounds() math32.Box3 {
+	return boundsOfWindings(s.Windings())
+}
+
+func boundsOfWindings(windings []*Winding) math32.Box3 {
+	bounds := math32.Box3{}
+	bounds.MakeEmpty()
+
+	for _, w := range windings {
+		if w == nil {
+			continue
+		}
+
+		mins, maxs := w.Bounds()
+		min := mins.Vector3()
+		max := maxs.Vector3()
+
+		bounds.ExpandByPoint(&min)
+		bounds.ExpandByPoint(&max)
+	}
+
+	return bounds
+}
+
 type Side struct {
 	Id              int
 	Plane           Plane
@@ -21,6 +136,10 @@ type Side struct {
 	Rotation        float32
 	LightmapScale   float32
 	SmoothingGroups bool
+
+	// The dispinfo block of this side if it has a displacement.
+	// Only its start position is changed when the solid is moved.
+	DispInfo *Block
 }
 
 type UVTransform struct {
@@ -30,16 +149,22 @@ type UVTransform struct {
 
 type Editor struct {
 	Color             math32.Vector3
+	GroupId           int
+	VisgroupIds       []int
 	visgroupShown     bool
 	visGroupAutoShown bool
 
 	logicalPos math32.Vector2 // only exists on brush entities?
 }
 
+// Plane normals point into the solid so points inside of
+// a solid are in front of every one of its planes
 type Plane struct {
 	Normal math32.Vector3
 	Dist   float32
-	Points [3]math32.Vector3
+	// Points that the plane goes through, kept in double precision
+	// so that they are saved exactly as they were loaded
+	Points [3]Vector3d
 }
 
 func NewSolid(id int, sides []Side, editor *Editor) *Solid {
@@ -71,31 +196,33 @@ func NewEditor(color math32.Vector3, visgroupShown bool, visgroupAutoShown bool)
 	}
 }
 
-func NewPlane(a math32.Vector3, b math32.Vector3, c math32.Vector3) *Plane {
-	x := b.Clone().Sub(&a)
-	y := c.Clone().Sub(&a)
-	normal := x.Clone().Cross(y).Normalize()
+func (e *Editor) VisgroupShown() bool {
+	return e.visgroupShown
+}
+
+func (e *Editor) VisgroupAutoShown() bool {
+	return e.visGroupAutoShown
+}
+
+func NewPlane(a Vector3d, b Vector3d, c Vector3d) *Plane {
+	// Do the maths in double precision so that the normal
+	// and distance are as close as they can be
+	precise := NewPlaneD(a, b, c)
 
 	p := Plane{
-		Points: [3]math32.Vector3{a, b, c},
-		Normal: *normal,
+		Points: [3]Vector3d{a, b, c},
+		Normal: precise.Normal.Vector3(),
+		Dist:   float32(precise.Dist),
 	}
 
-	p.Dist = p.Points[0].Dot(normal)
-
 	return &p
 }
 
 func NewPlaneFromString(marshalled string) *Plane {
-	var v1, v2, v3 = float32(0), float32(0), float32(0)
-	var v4, v5, v6 = float32(0), float32(0), float32(0)
-	var v7, v8, v9 = float32(0), float32(0), float32(0)
-	fmt.Sscanf(marshalled, "(%f %f %f) (%f %f %f) (%f %f %f)", &v1, &v2, &v3, &v4, &v5, &v6, &v7, &v8, &v9)
-
-	return NewPlane(
-		math32.Vector3{v1, v2, v3},
-		math32.Vector3{v4, v5, v6},
-		math32.Vector3{v7, v8, v9})
+	var a, b, c Vector3d
+	fmt.Sscanf(marshalled, "(%f %f %f) (%f %f %f) (%f %f %f)", &a.X, &a.Y, &a.Z, &b.X, &b.Y, &b.Z, &c.X, &c.Y, &c.Z)
+
+	return NewPlane(a, b, c)
 }
 
 func NewUVTransform(transform math32.Vector4, scale float32) *UVTransform {
@@ -0,0 +1,443 @@
+package world
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/emily33901/forgery/core/events"
+	"github.com/g3n/engine/math32"
+)
+
+const (
+	// Events
+
+	// WorldChanged tells other systems which solids an edit, undo or redo changed.
+	// It is sent once for each user action rather than for every command in it.
+	WorldChanged = "World.Changed"
+)
+
+type WorldChangedEvent struct {
+	World  *World
+	Solids []int
+}
+
+// DefaultHistoryDepth is how many user actions can be undone by default
+const DefaultHistoryDepth = 100
+
+// Command is a single reversible change to the world
+type Command interface {
+	Do(w *World)
+	Undo(w *World)
+
+	// Solids returns the ids of the solids that this command changes
+	Solids() []int
+}
+
+// Transaction is a group of commands that are
+// undone and redone as a single user action
+type Transaction struct {
+	Name     string
+	commands []Command
+}
+
+func (t *Transaction) solids() []int {
+	ids := []int{}
+	seen := map[int]bool{}
+
+	for _, c := range t.commands {
+		for _, id := range c.Solids() {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
+
+// History keeps track of what can be undone and redone
+type History struct {
+	undo  []*Transaction
+	redo  []*Transaction
+	depth int
+
+	// Transaction being built by Begin and Commit
+	open     *Transaction
+	openings int
+}
+
+func NewHistory(depth int) *History {
+	return &History{
+		depth: depth,
+	}
+}
+
+// SetDepth changes how many user actions are kept.
+// The oldest actions are forgotten first.
+func (h *History) SetDepth(depth int) {
+	h.depth = depth
+	h.trim()
+}
+
+func (h *History) trim() {
+	if h.depth >= 0 && len(h.undo) > h.depth {
+		h.undo = h.undo[len(h.undo)-h.depth:]
+	}
+}
+
+// CanUndo returns the name of the action that would be undone
+func (h *History) CanUndo() (string, bool) {
+	if len(h.undo) == 0 {
+		return "", false
+	}
+
+	return h.undo[len(h.undo)-1].Name, true
+}
+
+// CanRedo returns the name of the action that would be redone
+func (h *History) CanRedo() (string, bool) {
+	if len(h.redo) == 0 {
+		return "", false
+	}
+
+	return h.redo[len(h.redo)-1].Name, true
+}
+
+// History returns the edit history of this world
+func (w *World) History() *History {
+	return w.history
+}
+
+// Begin starts grouping commands into a single user action called name.
+// Begin and Commit can be nested, only the outermost name is kept.
+func (w *World) Begin(name string) {
+	h := w.history
+
+	if h.openings == 0 {
+		h.open = &Transaction{Name: name}
+	}
+
+	h.openings++
+}
+
+// Commit finishes the user action started by Begin
+func (w *World) Commit() {
+	h := w.history
+
+	if h.openings == 0 {
+		return
+	}
+
+	h.openings--
+	if h.openings != 0 {
+		return
+	}
+
+	t := h.open
+	h.open = nil
+
+	if len(t.commands) == 0 {
+		return
+	}
+
+	h.undo = append(h.undo, t)
+	h.redo = nil
+	h.trim()
+
+	w.worldChanged(t.solids())
+}
+
+// Execute runs c and records it so that it can be undone.
+// If there is no action started with Begin, c is its own action.
+func (w *World) Execute(name string, c Command) {
+	w.Begin(name)
+
+	c.Do(w)
+	w.history.open.commands = append(w.history.open.commands, c)
+
+	// Keep lookups up to date for the rest of the action
+	for _, id := range c.Solids() {
+		w.MakeSolidDirty(id)
+	}
+
+	w.Commit()
+}
+
+// Undo undoes the last user action
+func (w *World) Undo() bool {
+	h := w.history
+
+	if len(h.undo) == 0 || h.openings != 0 {
+		return false
+	}
+
+	t := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+
+	for i := len(t.commands) - 1; i >= 0; i-- {
+		t.commands[i].Undo(w)
+	}
+
+	h.redo = append(h.redo, t)
+
+	w.solidsChanged(t.solids())
+
+	return true
+}
+
+// Redo redoes the last user action that was undone
+func (w *World) Redo() bool {
+	h := w.history
+
+	if len(h.redo) == 0 || h.openings != 0 {
+		return false
+	}
+
+	t := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+
+	for _, c := range t.commands {
+		c.Do(w)
+	}
+
+	h.undo = append(h.undo, t)
+
+	w.solidsChanged(t.solids())
+
+	return true
+}
+
+func (w *World) solidsChanged(ids []int) {
+	for _, id := range ids {
+		w.MakeSolidDirty(id)
+	}
+
+	w.worldChanged(ids)
+}
+
+// worldChanged tells everything else once a user action is done
+func (w *World) worldChanged(ids []int) {
+	w.selection.prune()
+
+	events.Dispatch(WorldChanged, &WorldChangedEvent{
+		w, ids,
+	})
+}
+
+// cloneSolid deep copies s so that later changes to s do not affect it
+func cloneSolid(s *Solid) Solid {
+	c := *s
+	c.Sides = append([]Side(nil), s.Sides...)
+
+	if s.Editor != nil {
+		editor := *s.Editor
+		c.Editor = &editor
+	}
+
+	return c
+}
+
+type createSolidCommand struct {
+	solid Solid
+}
+
+func (c *createSolidCommand) Do(w *World) {
+	w.insertSolid(cloneSolid(&c.solid))
+}
+
+func (c *createSolidCommand) Undo(w *World) {
+	w.removeSolidById(c.solid.Id)
+}
+
+func (c *createSolidCommand) Solids() []int {
+	return []int{c.solid.Id}
+}
+
+type deleteSolidCommand struct {
+	solid Solid
+}
+
+func (c *deleteSolidCommand) Do(w *World) {
+	w.removeSolidById(c.solid.Id)
+}
+
+func (c *deleteSolidCommand) Undo(w *World) {
+	w.insertSolid(cloneSolid(&c.solid))
+}
+
+func (c *deleteSolidCommand) Solids() []int {
+	return []int{c.solid.Id}
+}
+
+// modifySolidCommand swaps a solid between two versions of itself.
+// Transforms, retextures and property changes are all this.
+type modifySolidCommand struct {
+	before Solid
+	after  Solid
+}
+
+func (c *modifySolidCommand) Do(w *World) {
+	w.replaceSolid(&c.after)
+}
+
+func (c *modifySolidCommand) Undo(w *World) {
+	w.replaceSolid(&c.before)
+}
+
+func (c *modifySolidCommand) Solids() []int {
+	return []int{c.after.Id}
+}
+
+// CreateSolid adds s to the world as an undoable action.
+// s is given new ids if it does not have any or they are already used.
+func (w *World) CreateSolid(s Solid) int {
+	s = cloneSolid(&s)
+
+	needsIds := s.Id <= 0 || w.Solid(s.Id) != nil
+	for _, side := range s.Sides {
+		if side.Id <= 0 {
+			needsIds = true
+		} else if other, _ := w.Side(side.Id); other != nil {
+			needsIds = true
+		}
+	}
+
+	if needsIds {
+		w.AssignIds(&s)
+	}
+
+	w.Execute("Create solid", &createSolidCommand{s})
+
+	return s.Id
+}
+
+// DeleteSolid removes the solid with id as an undoable action
+func (w *World) DeleteSolid(id int) {
+	s := w.Solid(id)
+	if s == nil {
+		return
+	}
+
+	w.Execute("Delete solid", &deleteSolidCommand{cloneSolid(s)})
+}
+
+// ModifySolid calls modify with a copy of the solid with id and
+// then replaces the solid with it as an undoable action called name.
+// The id of the solid must not be changed.
+func (w *World) ModifySolid(name string, id int, modify func(s *Solid)) {
+	s := w.Solid(id)
+	if s == nil {
+		return
+	}
+
+	before := cloneSolid(s)
+	after := cloneSolid(s)
+
+	modify(&after)
+	after.Id = id
+
+	w.Execute(name, &modifySolidCommand{before, after})
+}
+
+// TransformSolid moves every plane of the solid with id by m.
+// Texture axes are left alone.
+func (w *World) TransformSolid(id int, m *math32.Matrix4) {
+	w.ModifySolid("Transform", id, func(s *Solid) {
+		transformPlanes(s, m)
+	})
+}
+
+// transformPlanes moves every plane of s by m
+func transformPlanes(s *Solid, m *math32.Matrix4) {
+	// Mirroring turns the planes inside out
+	mirrored := m.Determinant() < 0
+
+	for i := range s.Sides {
+		points := s.Sides[i].Plane.Points
+
+		for j := range points {
+			points[j] = points[j].ApplyMatrix4(m)
+		}
+
+		if mirrored {
+			points[0], points[2] = points[2], points[0]
+		}
+
+		s.Sides[i].Plane = *NewPlane(points[0], points[1], points[2])
+
+		if s.Sides[i].DispInfo != nil {
+			s.Sides[i].DispInfo = transformDispInfo(s.Sides[i].DispInfo, m)
+		}
+	}
+}
+
+// transformDispInfo returns a copy of dispinfo with its start position
+// moved by m so that it stays on the same corner of the side.
+// The displacement itself is not rotated.
+func transformDispInfo(dispInfo *Block, m *math32.Matrix4) *Block {
+	c := cloneBlock(dispInfo)
+
+	start := Vector3d{}
+	if n, _ := fmt.Sscanf(c.Property("startposition"), "[%f %f %f]", &start.X, &start.Y, &start.Z); n != 3 {
+		return &c
+	}
+
+	start = start.ApplyMatrix4(m)
+
+	c.SetProperty("startposition", "["+
+		strconv.FormatFloat(start.X, 'f', -1, 64)+" "+
+		strconv.FormatFloat(start.Y, 'f', -1, 64)+" "+
+		strconv.FormatFloat(start.Z, 'f', -1, 64)+"]")
+
+	return &c
+}
+
+// RetextureSide changes the material of the side with id
+func (w *World) RetextureSide(id int, material string) {
+	s, _ := w.Side(id)
+	if s == nil {
+		return
+	}
+
+	w.ModifySolid("Retexture", s.Id, func(s *Solid) {
+		for i := range s.Sides {
+			if s.Sides[i].Id == id {
+				s.Sides[i].Material = material
+			}
+		}
+	})
+}
+
+func (w *World) insertSolid(s Solid) {
+	w.ids.ReserveObjectId(s.Id)
+	for _, side := range s.Sides {
+		w.ids.ReserveSideId(side.Id)
+	}
+
+	w.solids = append(w.solids, s)
+	w.indexSolid(len(w.solids) - 1)
+}
+
+// replaceSolid swaps the solid with the same id as s for a copy of s.
+// Only its sides are reindexed so that big transactions stay fast.
+func (w *World) replaceSolid(s *Solid) {
+	i, ok := w.solidsById[s.Id]
+	if !ok {
+		return
+	}
+
+	w.unindexSides(&w.solids[i])
+	w.solids[i] = cloneSolid(s)
+	w.indexSolid(i)
+}
+
+func (w *World) removeSolidById(id int) {
+	i, ok := w.solidsById[id]
+	if !ok {
+		return
+	}
+
+	w.solids = append(w.solids[:i], w.solids[i+1:]...)
+	w.reindex()
+}
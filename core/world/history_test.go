@@ -0,0 +1,210 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/emily33901/forgery/core/events"
+	"github.com/g3n/engine/math32"
+)
+
+// cube returns a solid with id whose sides are numbered from firstSide
+func cube(id int, firstSide int, mins, maxs Vector3d) Solid {
+	s := solidFromPlanes(boxPlanes(mins, maxs))
+	s.Id = id
+
+	for i := range s.Sides {
+		s.Sides[i].Id = firstSide + i
+	}
+
+	return *s
+}
+
+func newTestWorld(t *testing.T, solids ...Solid) *World {
+	t.Helper()
+
+	events.Init()
+
+	w := New(solids)
+	t.Cleanup(w.Close)
+
+	return w
+}
+
+func TestModifySolidKeepsLookups(t *testing.T) {
+	solids := []Solid{}
+	for i := 0; i < 50; i++ {
+		offset := float64(i * 128)
+		solids = append(solids, cube(i+1, i*6+1, Vector3d{offset, 0, 0}, Vector3d{offset + 64, 64, 64}))
+	}
+
+	w := newTestWorld(t, solids...)
+
+	m := math32.NewMatrix4().MakeTranslation(0, 0, 32)
+
+	w.Begin("Move everything")
+	for i := range solids {
+		w.TransformSolid(solids[i].Id, m)
+	}
+	w.Commit()
+
+	// Give the first solid a side with a new id
+	w.ModifySolid("Renumber", 1, func(s *Solid) {
+		s.Sides[0].Id = 1000
+	})
+
+	checkLookups := func(moved float64, renumbered bool) {
+		t.Helper()
+
+		for i := range solids {
+			s := w.Solid(solids[i].Id)
+			if s == nil {
+				t.Fatalf("solid %d is missing", solids[i].Id)
+			}
+
+			if z := s.Sides[0].Plane.Points[0].Z; z != 64+moved {
+				t.Errorf("solid %d top is at %v, want %v", s.Id, z, 64+moved)
+			}
+
+			for j := range s.Sides {
+				owner, side := w.Side(s.Sides[j].Id)
+				if owner != s || side != &s.Sides[j] {
+					t.Errorf("side %d is not found on solid %d", s.Sides[j].Id, s.Id)
+				}
+			}
+		}
+
+		if _, side := w.Side(1000); (side != nil) != renumbered {
+			t.Errorf("side 1000 found %v, want %v", side != nil, renumbered)
+		}
+
+		if _, side := w.Side(1); (side != nil) == renumbered {
+			t.Errorf("side 1 found %v, want %v", side != nil, !renumbered)
+		}
+	}
+
+	checkLookups(32, true)
+
+	w.Undo()
+	checkLookups(32, false)
+
+	w.Undo()
+	checkLookups(0, false)
+
+	w.Redo()
+	w.Redo()
+	checkLookups(32, true)
+}
+
+func TestWorldChangedOncePerAction(t *testing.T) {
+	solids := []Solid{}
+	for i := 0; i < 3; i++ {
+		offset := float64(i * 128)
+		solids = append(solids, cube(i+1, i*6+1, Vector3d{offset, 0, 0}, Vector3d{offset + 64, 64, 64}))
+	}
+
+	w := newTestWorld(t, solids...)
+
+	changes := [][]int{}
+	events.Subscribe(WorldChanged, func(_ string, ev interface{}) {
+		changes = append(changes, ev.(*WorldChangedEvent).Solids)
+	})
+
+	m := math32.NewMatrix4().MakeTranslation(0, 0, 32)
+
+	w.Begin("Move everything")
+	for i := range solids {
+		w.TransformSolid(solids[i].Id, m)
+		w.TransformSolid(solids[i].Id, m)
+	}
+
+	if len(changes) != 0 {
+		t.Fatalf("WorldChanged was sent %d times before the action was committed", len(changes))
+	}
+
+	w.Commit()
+
+	w.Undo()
+	w.Redo()
+
+	// An action with nothing in it changes nothing
+	w.Begin("Nothing")
+	w.Commit()
+
+	if len(changes) != 3 {
+		t.Fatalf("WorldChanged was sent %d times, want once each for the action, undo and redo", len(changes))
+	}
+
+	for i, ids := range changes {
+		if len(ids) != 3 {
+			t.Errorf("change %d has solids %v, want each solid once", i, ids)
+		}
+	}
+}
+
+func TestEntityProperty(t *testing.T) {
+	w := newTestWorld(t)
+
+	id := w.CreateEntity(Entity{
+		Classname:  "light",
+		Properties: map[string]string{"classname": "light", "_light": "255 255 255 200"},
+	})
+
+	changes := 0
+	events.Subscribe(WorldChanged, func(_ string, _ interface{}) {
+		changes++
+	})
+
+	w.SetEntityProperty(id, "_light", "255 0 0 200")
+	w.SetEntityProperty(id, "targetname", "red")
+	w.RemoveEntityProperty(id, "_light")
+	w.SetEntityProperty(id, "classname", "light_spot")
+
+	// Neither of these change anything so are not actions
+	w.SetEntityProperty(id, "targetname", "red")
+	w.RemoveEntityProperty(id, "_distance")
+
+	if changes != 4 {
+		t.Errorf("WorldChanged was sent %d times, want 4", changes)
+	}
+
+	check := func(classname string, want map[string]string) {
+		t.Helper()
+
+		e := w.Entity(id)
+		if e.Classname != classname {
+			t.Errorf("classname is %s, want %s", e.Classname, classname)
+		}
+
+		if len(e.Properties) != len(want) {
+			t.Errorf("properties are %v, want %v", e.Properties, want)
+			return
+		}
+
+		for k, v := range want {
+			if got, ok := e.Properties[k]; !ok || got != v {
+				t.Errorf("properties are %v, want %v", e.Properties, want)
+				return
+			}
+		}
+	}
+
+	check("light_spot", map[string]string{"classname": "light_spot", "targetname": "red"})
+
+	if name, _ := w.history.CanUndo(); name != "Set classname" {
+		t.Errorf("last action is %q", name)
+	}
+
+	w.Undo()
+	check("light", map[string]string{"classname": "light", "targetname": "red"})
+
+	w.Undo()
+	check("light", map[string]string{"classname": "light", "targetname": "red", "_light": "255 0 0 200"})
+
+	w.Undo()
+	w.Undo()
+	check("light", map[string]string{"classname": "light", "_light": "255 255 255 200"})
+
+	for w.Redo() {
+	}
+	check("light_spot", map[string]string{"classname": "light_spot", "targetname": "red"})
+}
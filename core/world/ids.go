@@ -0,0 +1,176 @@
+package world
+
+// IdAllocator hands out unique ids for a single map.
+// Like hammer, solids and entities share one range of ids
+// and sides have a range of their own.
+type IdAllocator struct {
+	lastObjectId int
+	lastSideId   int
+}
+
+// NewObjectId returns an unused solid or entity id
+func (a *IdAllocator) NewObjectId() int {
+	a.lastObjectId++
+	return a.lastObjectId
+}
+
+// NewSideId returns an unused side id
+func (a *IdAllocator) NewSideId() int {
+	a.lastSideId++
+	return a.lastSideId
+}
+
+// ReserveObjectId makes sure that id will never be handed out
+func (a *IdAllocator) ReserveObjectId(id int) {
+	if id > a.lastObjectId {
+		a.lastObjectId = id
+	}
+}
+
+// ReserveSideId makes sure that id will never be handed out
+func (a *IdAllocator) ReserveSideId(id int) {
+	if id > a.lastSideId {
+		a.lastSideId = id
+	}
+}
+
+// sideRef locates a side by the id of its
+// solid and its index in the solids sides
+type sideRef struct {
+	solidId int
+	index   int
+}
+
+// Ids returns the id allocator for this world
+func (w *World) Ids() *IdAllocator {
+	return &w.ids
+}
+
+// AssignIds gives s and all of its sides new ids.
+// Use this when solids are created, duplicated or pasted.
+func (w *World) AssignIds(s *Solid) {
+	s.Id = w.ids.NewObjectId()
+
+	for i := range s.Sides {
+		s.Sides[i].Id = w.ids.NewSideId()
+	}
+}
+
+// Side returns the side with id and the solid it belongs to
+func (w *World) Side(id int) (*Solid, *Side) {
+	ref, ok := w.sidesById[id]
+	if !ok {
+		return nil, nil
+	}
+
+	s := w.Solid(ref.solidId)
+	if s == nil || ref.index >= len(s.Sides) {
+		return nil, nil
+	}
+
+	return s, &s.Sides[ref.index]
+}
+
+// repairIds seeds the allocator from the loaded ids and gives new ids
+// to anything that is missing one or shares one with something else.
+// Entities and the solids of brush entities are included as they
+// share the same ids. It returns how many ids were changed.
+func (w *World) repairIds() int {
+	solids := []*Solid{}
+	for i := range w.solids {
+		solids = append(solids, &w.solids[i])
+	}
+
+	for i := range w.entities {
+		w.ids.ReserveObjectId(w.entities[i].Id)
+
+		for j := range w.entities[i].Solids {
+			solids = append(solids, &w.entities[i].Solids[j])
+		}
+	}
+
+	for _, s := range solids {
+		w.ids.ReserveObjectId(s.Id)
+
+		for _, side := range s.Sides {
+			w.ids.ReserveSideId(side.Id)
+		}
+	}
+
+	repaired := 0
+	seenObjects := map[int]bool{}
+	seenSides := map[int]bool{}
+
+	repairObject := func(id *int) {
+		if *id <= 0 || seenObjects[*id] {
+			*id = w.ids.NewObjectId()
+			repaired++
+		}
+
+		seenObjects[*id] = true
+	}
+
+	// World solids keep their ids over entities
+	for _, s := range solids[:len(w.solids)] {
+		repairObject(&s.Id)
+	}
+
+	for i := range w.entities {
+		repairObject(&w.entities[i].Id)
+	}
+
+	for _, s := range solids[len(w.solids):] {
+		repairObject(&s.Id)
+	}
+
+	for _, s := range solids {
+		for j := range s.Sides {
+			side := &s.Sides[j]
+
+			if side.Id <= 0 || seenSides[side.Id] {
+				side.Id = w.ids.NewSideId()
+				repaired++
+			}
+
+			seenSides[side.Id] = true
+		}
+	}
+
+	return repaired
+}
+
+// reindex rebuilds the id lookups.
+// This needs to be called whenever solids or entities are added or removed.
+func (w *World) reindex() {
+	w.solidsById = make(map[int]int, len(w.solids))
+	w.sidesById = map[int]sideRef{}
+
+	for i := range w.solids {
+		w.indexSolid(i)
+	}
+
+	w.entitiesById = make(map[int]int, len(w.entities))
+
+	for i := range w.entities {
+		w.entitiesById[w.entities[i].Id] = i
+	}
+}
+
+// indexSolid adds the solid at index i and its sides to the id lookups
+func (w *World) indexSolid(i int) {
+	s := &w.solids[i]
+	w.solidsById[s.Id] = i
+
+	for j := range s.Sides {
+		w.sidesById[s.Sides[j].Id] = sideRef{s.Id, j}
+	}
+}
+
+// unindexSides removes the sides of s from the id lookups
+func (w *World) unindexSides(s *Solid) {
+	for _, side := range s.Sides {
+		if ref, ok := w.sidesById[side.Id]; ok && ref.solidId == s.Id {
+			delete(w.sidesById, side.Id)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/emily33901/forgery/core/events"
+)
+
+func TestRepairIds(t *testing.T) {
+	events.Init()
+
+	solids := []Solid{
+		cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64}),
+		// Same ids as the first
+		cube(1, 1, Vector3d{128, 0, 0}, Vector3d{192, 64, 64}),
+		cube(0, 20, Vector3d{256, 0, 0}, Vector3d{320, 64, 64}),
+	}
+
+	entities := []Entity{
+		{Id: 2, Classname: "func_detail", Solids: []Solid{
+			// Same id as its entity and sides shared with a world solid
+			cube(2, 3, Vector3d{0, 128, 0}, Vector3d{64, 192, 64}),
+		}},
+		// Same id as a world solid
+		{Id: 1, Classname: "info_player_start"},
+		{Id: 0, Classname: "light"},
+		{Id: 30, Classname: "func_brush", Solids: []Solid{
+			cube(31, 40, Vector3d{0, 256, 0}, Vector3d{64, 320, 64}),
+		}},
+	}
+
+	w := NewWithEntities(solids, entities)
+	defer w.Close()
+
+	objects := map[int]bool{}
+	sides := map[int]bool{}
+
+	checkSolid := func(s *Solid) {
+		if s.Id <= 0 || objects[s.Id] {
+			t.Errorf("solid id %d is used more than once", s.Id)
+		}
+		objects[s.Id] = true
+
+		for _, side := range s.Sides {
+			if side.Id <= 0 || sides[side.Id] {
+				t.Errorf("side id %d is used more than once", side.Id)
+			}
+			sides[side.Id] = true
+		}
+	}
+
+	for _, s := range w.Solids() {
+		checkSolid(s)
+	}
+
+	for _, e := range w.Entities() {
+		if e.Id <= 0 || objects[e.Id] {
+			t.Errorf("entity id %d is used more than once", e.Id)
+		}
+		objects[e.Id] = true
+
+		if w.Entity(e.Id) != e {
+			t.Errorf("entity %d is not found by its id", e.Id)
+		}
+
+		for i := range e.Solids {
+			checkSolid(&e.Solids[i])
+		}
+	}
+
+	// Ids that were fine are kept, world solids first
+	if w.Solids()[0].Id != 1 || w.Entities()[0].Id != 2 || w.Entities()[3].Id != 30 || w.Entities()[3].Solids[0].Id != 31 {
+		t.Errorf("ids that were not duplicated were changed")
+	}
+
+	// New ids do not clash with anything loaded
+	if id := w.Ids().NewObjectId(); objects[id] || id <= 31 {
+		t.Errorf("allocator handed out used object id %d", id)
+	}
+
+	if id := w.Ids().NewSideId(); sides[id] || id <= 45 {
+		t.Errorf("allocator handed out used side id %d", id)
+	}
+}
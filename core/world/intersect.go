@@ -0,0 +1,85 @@
+package world
+
+import (
+	"math"
+
+	"github.com/g3n/engine/math32"
+)
+
+// RayPlaneHit is where a ray crosses one of the planes of a solid
+type RayPlaneHit struct {
+	SideId   int
+	Plane    Plane
+	Distance float32
+}
+
+// SolidIntersection describes the section of a ray that is inside of a solid
+type SolidIntersection struct {
+	Enter RayPlaneHit
+	Exit  RayPlaneHit
+}
+
+// IntersectRay intersects a ray with this solid using its side planes directly.
+// Distances are along the normalised direction and Enter.Distance is negative
+// when the origin is inside of the solid.
+func (s *Solid) IntersectRay(origin, direction math32.Vector3) (SolidIntersection, bool) {
+	direction.Normalize()
+
+	return intersectRaySides(s.Sides, &origin, &direction)
+}
+
+func intersectRaySides(sides []Side, origin, direction *math32.Vector3) (SolidIntersection, bool) {
+	result := SolidIntersection{
+		Enter: RayPlaneHit{Distance: float32(math.Inf(-1))},
+		Exit:  RayPlaneHit{Distance: float32(math.Inf(1))},
+	}
+
+	valid := false
+
+	for i := range sides {
+		side := &sides[i]
+
+		if side.Plane.Normal.LengthSq() == 0 {
+			// Not a valid plane
+			continue
+		}
+
+		valid = true
+
+		// Positive is inside of the solid
+		start := side.Plane.Normal.Dot(origin) - side.Plane.Dist
+		denom := side.Plane.Normal.Dot(direction)
+
+		if denom == 0 {
+			// Parallel so either always in front or always behind
+			if start < 0 {
+				return result, false
+			}
+
+			continue
+		}
+
+		dist := -start / denom
+
+		if denom > 0 {
+			// Moving towards the inside so this is where we enter
+			if dist > result.Enter.Distance {
+				result.Enter = RayPlaneHit{side.Id, side.Plane, dist}
+			}
+		} else {
+			if dist < result.Exit.Distance {
+				result.Exit = RayPlaneHit{side.Id, side.Plane, dist}
+			}
+		}
+
+		if result.Enter.Distance > result.Exit.Distance {
+			return result, false
+		}
+	}
+
+	if !valid || result.Exit.Distance < 0 {
+		return result, false
+	}
+
+	return result, true
+}
@@ -0,0 +1,62 @@
+package world
+
+import (
+	"math"
+	"testing"
+
+	"github.com/g3n/engine/math32"
+)
+
+func TestIntersectRay(t *testing.T) {
+	box := cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64})
+
+	// Turned 45 degrees about z so that it is a diamond from above
+	rotated := cube(2, 1, Vector3d{-32, -32, -32}, Vector3d{32, 32, 32})
+	transformPlanes(&rotated, math32.NewMatrix4().MakeRotationZ(math.Pi/4))
+
+	halfDiagonal := float32(32 * math.Sqrt2)
+
+	tests := []struct {
+		name      string
+		solid     *Solid
+		origin    math32.Vector3
+		direction math32.Vector3
+		hit       bool
+		// Side ids and distances, only checked if hit
+		enterSide, exitSide int
+		enter, exit         float32
+	}{
+		{"through", &box, math32.Vector3{-32, 32, 32}, math32.Vector3{1, 0, 0}, true, 3, 4, 32, 96},
+		{"down", &box, math32.Vector3{16, 16, 100}, math32.Vector3{0, 0, -1}, true, 1, 2, 36, 100},
+		{"direction is normalised", &box, math32.Vector3{32, -32, 32}, math32.Vector3{0, 8, 0}, true, 6, 5, 32, 96},
+		{"inside", &box, math32.Vector3{16, 32, 32}, math32.Vector3{1, 0, 0}, true, 3, 4, -16, 48},
+		{"parallel and above", &box, math32.Vector3{-32, 32, 100}, math32.Vector3{1, 0, 0}, false, 0, 0, 0, 0},
+		{"parallel and on a face", &box, math32.Vector3{-32, 32, 64}, math32.Vector3{1, 0, 0}, true, 3, 4, 32, 96},
+		{"pointing away", &box, math32.Vector3{-32, 32, 32}, math32.Vector3{-1, 0, 0}, false, 0, 0, 0, 0},
+		{"past the corner", &box, math32.Vector3{-32, 100, 32}, math32.Vector3{1, 1, 0}, false, 0, 0, 0, 0},
+		// At y = 10 the diamond goes from the back face on the left to the right face
+		{"rotated", &rotated, math32.Vector3{-100, 10, 0}, math32.Vector3{1, 0, 0}, true, 5, 4, 100 - (halfDiagonal - 10), 100 + (halfDiagonal - 10)},
+		{"rotated corner missed", &rotated, math32.Vector3{-100, 40, 0}, math32.Vector3{1, 0, 1}, false, 0, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, ok := test.solid.IntersectRay(test.origin, test.direction)
+			if ok != test.hit {
+				t.Fatalf("hit = %v, want %v (%+v)", ok, test.hit, result)
+			}
+
+			if !ok {
+				return
+			}
+
+			if result.Enter.SideId != test.enterSide || result.Exit.SideId != test.exitSide {
+				t.Errorf("entered side %d and left side %d, want %d and %d", result.Enter.SideId, result.Exit.SideId, test.enterSide, test.exitSide)
+			}
+
+			if math32.Abs(result.Enter.Distance-test.enter) > 1e-3 || math32.Abs(result.Exit.Distance-test.exit) > 1e-3 {
+				t.Errorf("distances are %v and %v, want %v and %v", result.Enter.Distance, result.Exit.Distance, test.enter, test.exit)
+			}
+		})
+	}
+}
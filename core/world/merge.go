@@ -0,0 +1,335 @@
+package world
+
+import (
+	"errors"
+)
+
+// ErrNotConvex is returned when solids that are not convex
+// together are merged without asking for their hull
+var ErrNotConvex = errors.New("solids are not convex together")
+
+// Any volume left over smaller than this is a sliver from clipping
+const mergeVolumeEpsilon = 1.0
+
+// MergeSolids replaces the solids with ids with a single solid as one undoable
+// action and returns its id. Unless hull is set the solids have to be convex
+// together, otherwise ErrNotConvex is returned and nothing is changed. With hull
+// set they are replaced by their convex hull instead.
+// Each face of the new solid keeps the material and texture axes of the
+// biggest original face on the same plane, or the closest one if there is none.
+// Displacements are not kept as the new faces are a different shape.
+func (w *World) MergeSolids(ids []int, hull bool) (int, error) {
+	solids := []*Solid{}
+	solidIds := []int{}
+
+	for _, id := range ids {
+		if s := w.Solid(id); s != nil {
+			solids = append(solids, s)
+			solidIds = append(solidIds, id)
+		}
+	}
+
+	if len(solids) < 2 {
+		return 0, errors.New("need at least 2 solids to merge")
+	}
+
+	merged, err := mergeSolids(solids, hull)
+	if err != nil {
+		return 0, err
+	}
+
+	w.Begin("Merge solids")
+
+	for _, id := range solidIds {
+		w.DeleteSolid(id)
+	}
+
+	id := w.CreateSolid(merged)
+
+	w.Commit()
+
+	w.selection.SelectSolids(SelectReplace, id)
+
+	return id, nil
+}
+
+// mergeFace is a face of one of the solids being merged
+type mergeFace struct {
+	side  *Side
+	plane PlaneD
+	area  float64
+}
+
+// mergeSolids builds the merged solid without changing the world.
+// The new solid and its sides have no ids.
+func mergeSolids(solids []*Solid, hull bool) (Solid, error) {
+	faces := []mergeFace{}
+	points := []Vector3d{}
+
+	for _, s := range solids {
+		for i, winding := range s.Windings() {
+			if winding == nil {
+				continue
+			}
+
+			faces = append(faces, mergeFace{&s.Sides[i], s.Sides[i].Plane.Precise(), winding.Area()})
+
+			for _, p := range winding.Points {
+				points = appendUniquePoint(points, p)
+			}
+		}
+	}
+
+	// If the solids are convex together then every face of the result
+	// is on the plane of an original face. A hull can need new planes.
+	candidates := make([]PlaneD, len(faces))
+	for i := range faces {
+		candidates[i] = faces[i].plane
+	}
+
+	if hull {
+		candidates = append(candidates, hullPlanes(points)...)
+	}
+
+	planes := []PlaneD{}
+
+	for _, p := range candidates {
+		if planeSupports(&p, points) && !containsPlane(planes, &p) {
+			planes = append(planes, p)
+		}
+	}
+
+	if !hull {
+		// Convex together if nothing of the result is outside of every original
+		pieces := [][]PlaneD{planes}
+
+		for _, s := range solids {
+			cut := make([]PlaneD, len(s.Sides))
+			for i := range s.Sides {
+				cut[i] = s.Sides[i].Plane.Precise()
+			}
+
+			remaining := [][]PlaneD{}
+			for _, piece := range pieces {
+				remaining = append(remaining, subtractPolytope(piece, cut)...)
+			}
+
+			pieces = remaining
+		}
+
+		if len(pieces) != 0 {
+			return Solid{}, ErrNotConvex
+		}
+	}
+
+	merged := Solid{}
+
+	if solids[0].Editor != nil {
+		editor := *solids[0].Editor
+		merged.Editor = &editor
+	}
+
+	usePlane := make([]bool, len(planes))
+	for i := range usePlane {
+		usePlane[i] = true
+	}
+
+	for i, winding := range windingsOfPlanes(planes, usePlane) {
+		if winding == nil {
+			continue
+		}
+
+		side := *closestFace(faces, &planes[i]).side
+		side.Id = 0
+		side.Plane = planeFromWinding(winding, &planes[i])
+
+		// A displacement only fits the face it was made for
+		side.DispInfo = nil
+
+		merged.Sides = append(merged.Sides, side)
+	}
+
+	return merged, nil
+}
+
+func appendUniquePoint(points []Vector3d, p Vector3d) []Vector3d {
+	for _, other := range points {
+		if other.Sub(p).Length() < splitEpsilon {
+			return points
+		}
+	}
+
+	return append(points, p)
+}
+
+// hullPlanes returns every plane through 3 of points that has
+// all of points in front of it. Slow but there are never many points.
+func hullPlanes(points []Vector3d) []PlaneD {
+	planes := []PlaneD{}
+
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			for k := j + 1; k < len(points); k++ {
+				p := NewPlaneD(points[i], points[j], points[k])
+				if p.Normal.LengthSq() == 0 {
+					// Points are in a line
+					continue
+				}
+
+				if !planeSupports(&p, points) {
+					p = flipPlane(p)
+
+					if !planeSupports(&p, points) {
+						continue
+					}
+				}
+
+				if !containsPlane(planes, &p) {
+					planes = append(planes, p)
+				}
+			}
+		}
+	}
+
+	return planes
+}
+
+// planeSupports checks that none of points are behind p
+func planeSupports(p *PlaneD, points []Vector3d) bool {
+	for _, point := range points {
+		if p.DistanceTo(point) < -splitEpsilon {
+			return false
+		}
+	}
+
+	return true
+}
+
+func samePlane(a, b *PlaneD) bool {
+	return a.Normal.Dot(b.Normal) > 0.9999 &&
+		a.Dist-b.Dist < splitEpsilon && a.Dist-b.Dist > -splitEpsilon
+}
+
+func containsPlane(planes []PlaneD, p *PlaneD) bool {
+	for i := range planes {
+		if samePlane(&planes[i], p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func flipPlane(p PlaneD) PlaneD {
+	return PlaneD{
+		Normal: p.Normal.Scale(-1),
+		Dist:   -p.Dist,
+	}
+}
+
+// closestFace returns the biggest face on plane, or the face
+// that faces the most like plane if none are on it
+func closestFace(faces []mergeFace, plane *PlaneD) *mergeFace {
+	var best *mergeFace
+	bestOn := false
+	bestDot := 0.0
+
+	for i := range faces {
+		f := &faces[i]
+		on := samePlane(&f.plane, plane)
+		dot := f.plane.Normal.Dot(plane.Normal)
+
+		switch {
+		case best == nil,
+			on && !bestOn,
+			on && bestOn && f.area > best.area,
+			!on && !bestOn && dot > bestDot:
+			best, bestOn, bestDot = f, on, dot
+		}
+	}
+
+	return best
+}
+
+// planeFromWinding picks 3 points of winding that make plane.
+// Vmf planes are stored as points so they are needed for saving.
+func planeFromWinding(winding *Winding, plane *PlaneD) Plane {
+	w := winding.Clone()
+	w.RemoveColinearPoints()
+
+	n := len(w.Points)
+	a := w.Points[0]
+	b := w.Points[n/3]
+	c := w.Points[2*n/3]
+
+	p := NewPlane(a, b, c)
+	if p.Precise().Normal.Dot(plane.Normal) < 0 {
+		p = NewPlane(a, c, b)
+	}
+
+	return *p
+}
+
+// polytopeVolume returns the volume of the convex space in front of every plane
+func polytopeVolume(planes []PlaneD) float64 {
+	usePlane := make([]bool, len(planes))
+
+	for i := range planes {
+		usePlane[i] = !containsPlane(planes[:i], &planes[i])
+	}
+
+	windings := windingsOfPlanes(planes, usePlane)
+
+	centre := Vector3d{}
+	count := 0
+
+	for _, w := range windings {
+		if w == nil {
+			continue
+		}
+
+		for _, p := range w.Points {
+			centre = centre.Add(p)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	centre = centre.Scale(1 / float64(count))
+
+	// Sum of the pyramids from the centre to each face
+	volume := 0.0
+
+	for i, w := range windings {
+		if w != nil {
+			volume += w.Area() * planes[i].DistanceTo(centre) / 3
+		}
+	}
+
+	return volume
+}
+
+// subtractPolytope returns convex pieces that make up the part of
+// piece that is outside of the convex space in front of every one of cut
+func subtractPolytope(piece []PlaneD, cut []PlaneD) [][]PlaneD {
+	outside := [][]PlaneD{}
+	inside := piece
+
+	for _, p := range cut {
+		back := append(append([]PlaneD(nil), inside...), flipPlane(p))
+		if polytopeVolume(back) > mergeVolumeEpsilon {
+			outside = append(outside, back)
+		}
+
+		inside = append(append([]PlaneD(nil), inside...), p)
+		if polytopeVolume(inside) <= mergeVolumeEpsilon {
+			return outside
+		}
+	}
+
+	// Whatever is left is inside
+	return outside
+}
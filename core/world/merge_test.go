@@ -0,0 +1,176 @@
+package world
+
+import (
+	"math"
+	"testing"
+
+	"github.com/g3n/engine/math32"
+)
+
+// sideFacing returns the side of s whose plane normal is closest to normal
+func sideFacing(s *Solid, normal Vector3d) *Side {
+	var best *Side
+	bestDot := -2.0
+
+	for i := range s.Sides {
+		if dot := s.Sides[i].Plane.Precise().Normal.Dot(normal); dot > bestDot {
+			best, bestDot = &s.Sides[i], dot
+		}
+	}
+
+	return best
+}
+
+// containsPoint returns whether p is inside of or on every plane of s
+func containsPoint(s *Solid, p Vector3d) bool {
+	for i := range s.Sides {
+		plane := s.Sides[i].Plane.Precise()
+		if plane.DistanceTo(p) < -splitEpsilon {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestMergeAdjacentBoxes(t *testing.T) {
+	small := cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64})
+	big := cube(2, 7, Vector3d{64, 0, 0}, Vector3d{192, 64, 64})
+
+	// Normals point into the solid so the top faces point down
+	up := Vector3d{0, 0, -1}
+
+	smallTop := sideFacing(&small, up)
+	smallTop.Material = "DEV/SMALL"
+	smallTop.UAxis = UVTransform{math32.Vector4{1, 0, 0, 16}, 0.5}
+
+	bigTop := sideFacing(&big, up)
+	bigTop.Material = "DEV/BIG"
+	bigTop.UAxis = UVTransform{math32.Vector4{1, 0, 0, 32}, 0.25}
+
+	// A displacement cannot be stretched over the merged face
+	bigTop.DispInfo = &Block{Key: "dispinfo", Properties: []Property{{"power", "2"}}}
+
+	w := newTestWorld(t, small, big)
+
+	id, err := w.MergeSolids([]int{1, 2}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.Solids()) != 1 {
+		t.Fatalf("merging left %d solids", len(w.Solids()))
+	}
+
+	merged := w.Solid(id)
+	if merged == nil {
+		t.Fatalf("merged solid %d is missing", id)
+	}
+
+	if len(merged.Sides) != 6 {
+		t.Errorf("merged box has %d sides, want 6", len(merged.Sides))
+	}
+
+	if mins, maxs := boundsOfSolid(merged); mins != (Vector3d{0, 0, 0}) || maxs != (Vector3d{192, 64, 64}) {
+		t.Errorf("merged box is %v %v", mins, maxs)
+	}
+
+	top := sideFacing(merged, up)
+	if top.Material != "DEV/BIG" || top.UAxis != bigTop.UAxis {
+		t.Errorf("top face has %s %+v, want the material and uvs of the biggest top face", top.Material, top.UAxis)
+	}
+
+	for _, side := range merged.Sides {
+		if side.DispInfo != nil {
+			t.Errorf("side %d kept a displacement", side.Id)
+		}
+	}
+
+	if selected := w.Selection().Solids(); len(selected) != 1 || selected[0] != id {
+		t.Errorf("selection is %v, want the merged solid", selected)
+	}
+
+	w.Undo()
+
+	if len(w.Solids()) != 2 || w.Solid(1) == nil || w.Solid(2) == nil || w.Solid(id) != nil {
+		t.Fatalf("undo left %d solids", len(w.Solids()))
+	}
+
+	if mins, maxs := boundsOfSolid(w.Solid(2)); mins != (Vector3d{64, 0, 0}) || maxs != (Vector3d{192, 64, 64}) {
+		t.Errorf("undone solid is %v %v", mins, maxs)
+	}
+
+	if sideFacing(w.Solid(2), up).DispInfo == nil {
+		t.Error("undo lost the displacement of the original face")
+	}
+}
+
+// lShape returns 2 solids that make an L when seen from above
+func lShape() []Solid {
+	return []Solid{
+		cube(1, 1, Vector3d{0, 0, 0}, Vector3d{128, 64, 64}),
+		cube(2, 7, Vector3d{0, 64, 0}, Vector3d{64, 128, 64}),
+	}
+}
+
+func TestMergeNotConvex(t *testing.T) {
+	w := newTestWorld(t, lShape()...)
+
+	if _, err := w.MergeSolids([]int{1, 2}, false); err != ErrNotConvex {
+		t.Fatalf("merging an L returned %v, want ErrNotConvex", err)
+	}
+
+	if len(w.Solids()) != 2 || w.Solid(1) == nil || w.Solid(2) == nil {
+		t.Errorf("failed merge changed the solids")
+	}
+
+	if _, ok := w.history.CanUndo(); ok {
+		t.Error("failed merge left something to undo")
+	}
+}
+
+func TestMergeHull(t *testing.T) {
+	w := newTestWorld(t, lShape()...)
+
+	id, err := w.MergeSolids([]int{1, 2}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hull := w.Solid(id)
+
+	// Top, bottom and 5 around the outside including the diagonal
+	if len(hull.Sides) != 7 {
+		t.Errorf("hull has %d sides, want 7", len(hull.Sides))
+	}
+
+	for _, s := range lShape() {
+		for _, winding := range s.Windings() {
+			for _, p := range winding.Points {
+				if !containsPoint(hull, p) {
+					t.Errorf("hull does not contain %v", p)
+				}
+			}
+		}
+	}
+
+	// In the corner of the L
+	if !containsPoint(hull, Vector3d{90, 90, 32}) {
+		t.Error("hull does not fill in the corner of the L")
+	}
+
+	if containsPoint(hull, Vector3d{100, 100, 32}) {
+		t.Error("hull goes past the diagonal")
+	}
+
+	diagonal := sideFacing(hull, Vector3d{-math.Sqrt2 / 2, -math.Sqrt2 / 2, 0})
+	if n := diagonal.Plane.Precise().Normal; math.Abs(n.X+math.Sqrt2/2) > 1e-6 || math.Abs(n.Y+math.Sqrt2/2) > 1e-6 {
+		t.Errorf("diagonal face has normal %v", n)
+	}
+
+	w.Undo()
+
+	if len(w.Solids()) != 2 || w.Solid(1) == nil || w.Solid(2) == nil {
+		t.Errorf("undo left %d solids", len(w.Solids()))
+	}
+}
@@ -0,0 +1,196 @@
+package world
+
+import (
+	"strings"
+
+	"github.com/g3n/engine/math32"
+)
+
+// NodrawMaterial is what hidden faces are retextured with
+const NodrawMaterial = "tools/toolsnodraw"
+
+// Anything smaller than this left visible on a face is
+// treated as a sliver from clipping and ignored
+const hiddenAreaEpsilon = 0.1
+
+// HiddenFace is a face that cannot be seen because it is
+// completely covered by the faces of other solids
+type HiddenFace struct {
+	SolidId int
+	SideId  int
+
+	// Material the face had before it was nodrawed
+	Material string
+	Area     float64
+}
+
+// FindHiddenFaces finds every face of the solids with ids that is completely
+// covered by touching coplanar faces of other solids. If ids is empty every
+// solid in the world is checked. Nothing is changed so that the faces can be
+// previewed before they are given to ApplyNodraw.
+func (w *World) FindHiddenFaces(ids []int) []HiddenFace {
+	if len(ids) == 0 {
+		ids = w.solidsWhere(func(*Solid) bool { return true })
+	}
+
+	hidden := []HiddenFace{}
+
+	for _, id := range ids {
+		s := w.Solid(id)
+		if s == nil {
+			continue
+		}
+
+		for i, winding := range s.Windings() {
+			side := &s.Sides[i]
+
+			if winding == nil || strings.EqualFold(side.Material, NodrawMaterial) {
+				continue
+			}
+
+			if faceHidden(side, winding, w.neighbours(s, winding)) {
+				hidden = append(hidden, HiddenFace{
+					SolidId:  s.Id,
+					SideId:   side.Id,
+					Material: side.Material,
+					Area:     winding.Area(),
+				})
+			}
+		}
+	}
+
+	return hidden
+}
+
+// ApplyNodraw retextures faces with NodrawMaterial as a single undoable action
+func (w *World) ApplyNodraw(faces []HiddenFace) {
+	bySolid := map[int]map[int]bool{}
+	order := []int{}
+
+	for _, face := range faces {
+		if bySolid[face.SolidId] == nil {
+			bySolid[face.SolidId] = map[int]bool{}
+			order = append(order, face.SolidId)
+		}
+
+		bySolid[face.SolidId][face.SideId] = true
+	}
+
+	w.Begin("Nodraw hidden faces")
+
+	for _, id := range order {
+		sides := bySolid[id]
+
+		w.ModifySolid("Nodraw hidden faces", id, func(s *Solid) {
+			for i := range s.Sides {
+				if sides[s.Sides[i].Id] {
+					s.Sides[i].Material = NodrawMaterial
+				}
+			}
+		})
+	}
+
+	w.Commit()
+}
+
+// neighbours returns the other solids that could touch winding
+func (w *World) neighbours(s *Solid, winding *Winding) []*Solid {
+	mins, maxs := winding.Bounds()
+	box := math32.Box3{Min: mins.Vector3(), Max: maxs.Vector3()}
+	box.ExpandByScalar(1)
+
+	solids := []*Solid{}
+
+	for _, id := range w.Spatial().QueryBox(box) {
+		if other := w.Solid(id); other != nil && id != s.Id {
+			solids = append(solids, other)
+		}
+	}
+
+	return solids
+}
+
+// faceHidden checks whether every part of winding (the face of side) is
+// inside one of neighbours that touches it with an opposite coplanar face
+func faceHidden(side *Side, winding *Winding, neighbours []*Solid) bool {
+	plane := side.Plane.Precise()
+
+	visible := []*Winding{winding}
+
+	for _, other := range neighbours {
+		for j := range other.Sides {
+			otherSide := &other.Sides[j]
+			otherPlane := otherSide.Plane.Precise()
+
+			if !occludes(otherSide.Material) || !oppositePlanes(&plane, &otherPlane) {
+				continue
+			}
+
+			planes := make([]PlaneD, 0, len(other.Sides)-1)
+			for k := range other.Sides {
+				if k != j {
+					planes = append(planes, other.Sides[k].Plane.Precise())
+				}
+			}
+
+			remaining := []*Winding{}
+			for _, v := range visible {
+				remaining = append(remaining, subtractPlanes(v, planes)...)
+			}
+
+			visible = remaining
+		}
+	}
+
+	area := 0.0
+	for _, v := range visible {
+		area += v.Area()
+	}
+
+	return area < hiddenAreaEpsilon
+}
+
+// oppositePlanes checks whether a and b are the same plane facing
+// opposite ways, which is what two touching faces look like
+func oppositePlanes(a, b *PlaneD) bool {
+	const (
+		normalEpsilon = 0.0001
+		distEpsilon   = splitEpsilon
+	)
+
+	return a.Normal.Dot(b.Normal) < -1+normalEpsilon &&
+		a.Dist+b.Dist < distEpsilon && a.Dist+b.Dist > -distEpsilon
+}
+
+// occludes returns whether a face with material hides what is behind it.
+// Tool textures other than nodraw are not drawn so they hide nothing.
+// Translucent materials are not detected.
+func occludes(material string) bool {
+	material = strings.ToLower(material)
+
+	return material == NodrawMaterial || !strings.HasPrefix(material, "tools/")
+}
+
+// subtractPlanes returns the parts of w that are outside of
+// the convex volume in front of every one of planes
+func subtractPlanes(w *Winding, planes []PlaneD) []*Winding {
+	outside := []*Winding{}
+	inside := w
+
+	for i := range planes {
+		front, back := inside.Split(&planes[i])
+
+		if back != nil {
+			outside = append(outside, back)
+		}
+
+		if front == nil {
+			return outside
+		}
+
+		inside = front
+	}
+
+	// Whatever is left is covered
+	return outside
+}
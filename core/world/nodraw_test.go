@@ -0,0 +1,31 @@
+package world
+
+import "testing"
+
+func TestFindHiddenFaces(t *testing.T) {
+	a := cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64})
+	b := cube(2, 7, Vector3d{64, 0, 0}, Vector3d{128, 64, 64})
+
+	for i := range a.Sides {
+		a.Sides[i].Material = "DEV/DEV_MEASUREGENERIC01B"
+		b.Sides[i].Material = "DEV/DEV_MEASUREGENERIC01B"
+	}
+
+	// Faces are in the order top, bottom, left, right, back, front
+	// so a's right face touches b's left face. Hammer writes
+	// materials in upper case so a's is already nodraw.
+	a.Sides[3].Material = "TOOLS/TOOLSNODRAW"
+
+	w := newTestWorld(t, a, b)
+
+	hidden := w.FindHiddenFaces(nil)
+	if len(hidden) != 1 || hidden[0].SideId != 9 {
+		t.Fatalf("hidden faces = %+v, want only side 9", hidden)
+	}
+
+	w.ApplyNodraw(hidden)
+
+	if len(w.FindHiddenFaces(nil)) != 0 {
+		t.Errorf("faces are still hidden after nodrawing them")
+	}
+}
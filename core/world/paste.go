@@ -0,0 +1,104 @@
+package world
+
+import (
+	"github.com/g3n/engine/math32"
+)
+
+// PasteOptions are the paste special settings.
+// The zero value pastes a single copy in place.
+type PasteOptions struct {
+	// Copies is how many times to paste, at least 1 copy is always made
+	Copies int
+
+	// Offset and Rotation (euler angles in degrees) are applied once
+	// per copy, so the first copy is moved once and the n-th copy n times
+	Offset   math32.Vector3
+	Rotation math32.Vector3
+}
+
+// Paste adds copies of solids and entities to the world as a single undoable
+// action and selects them. Every copy is given new ids so they can be pasted
+// into the map they were copied from. Rotation is about the centre of
+// everything being pasted. It returns the ids of the new solids and entities.
+func (w *World) Paste(solids []Solid, entities []Entity, opts PasteOptions) (solidIds []int, entityIds []int) {
+	if len(solids) == 0 && len(entities) == 0 {
+		return nil, nil
+	}
+
+	copies := opts.Copies
+	if copies < 1 {
+		copies = 1
+	}
+
+	bounds := math32.Box3{}
+	bounds.MakeEmpty()
+
+	for i := range solids {
+		b := solids[i].Bounds()
+		bounds.Union(&b)
+	}
+
+	for i := range entities {
+		if origin, ok := entities[i].Origin(); ok {
+			v := origin.Vector3()
+			bounds.ExpandByPoint(&v)
+		}
+
+		for j := range entities[i].Solids {
+			b := entities[i].Solids[j].Bounds()
+			bounds.Union(&b)
+		}
+	}
+
+	var centre math32.Vector3
+	if !bounds.Empty() {
+		bounds.Center(&centre)
+	}
+
+	w.Begin("Paste")
+
+	for n := 1; n <= copies; n++ {
+		m := pasteTransform(&centre, &opts, float32(n))
+
+		for i := range solids {
+			s := cloneSolid(&solids[i])
+
+			w.AssignIds(&s)
+			transformPlanes(&s, m)
+
+			solidIds = append(solidIds, w.CreateSolid(s))
+		}
+
+		for i := range entities {
+			e := cloneEntity(&entities[i])
+
+			w.AssignEntityIds(&e)
+			transformEntity(&e, m)
+
+			entityIds = append(entityIds, w.CreateEntity(e))
+		}
+	}
+
+	w.Commit()
+
+	w.selection.SelectSolids(SelectReplace, solidIds...)
+	w.selection.SelectEntities(SelectAdd, entityIds...)
+
+	return solidIds, entityIds
+}
+
+// pasteTransform returns the matrix that moves the n-th copy into place
+func pasteTransform(centre *math32.Vector3, opts *PasteOptions, n float32) *math32.Matrix4 {
+	euler := opts.Rotation
+	euler.MultiplyScalar(n * math32.Pi / 180)
+
+	offset := opts.Offset
+	offset.MultiplyScalar(n)
+	offset.Add(centre)
+
+	m := math32.NewMatrix4().MakeTranslation(offset.X, offset.Y, offset.Z)
+	m.Multiply(math32.NewMatrix4().MakeRotationFromEuler(&euler))
+	m.Multiply(math32.NewMatrix4().MakeTranslation(-centre.X, -centre.Y, -centre.Z))
+
+	return m
+}
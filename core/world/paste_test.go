@@ -0,0 +1,113 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/g3n/engine/math32"
+)
+
+func TestPasteCopies(t *testing.T) {
+	w := newTestWorld(t, cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64}))
+
+	solids := []Solid{*w.Solid(1)}
+	entities := []Entity{
+		{Id: 2, Classname: "info_player_start", Properties: map[string]string{"origin": "32 32 65"}},
+		{Id: 3, Classname: "func_detail", Solids: []Solid{cube(4, 7, Vector3d{0, 0, 64}, Vector3d{16, 16, 80})}},
+	}
+
+	solidIds, entityIds := w.Paste(solids, entities, PasteOptions{
+		Copies: 3,
+		Offset: math32.Vector3{128, 0, 0},
+	})
+
+	if len(solidIds) != 3 || len(entityIds) != 6 {
+		t.Fatalf("pasted %d solids and %d entities, want 3 and 6", len(solidIds), len(entityIds))
+	}
+
+	objects := map[int]bool{1: true}
+
+	for n, id := range solidIds {
+		if objects[id] {
+			t.Errorf("pasted solid has used id %d", id)
+		}
+		objects[id] = true
+
+		// The first copy is moved once
+		want := float64(128 * (n + 1))
+
+		mins, _ := boundsOfSolid(w.Solid(id))
+		if mins.X != want {
+			t.Errorf("copy %d starts at x %v, want %v", n+1, mins.X, want)
+		}
+	}
+
+	for n, id := range entityIds {
+		e := w.Entity(id)
+		if e == nil {
+			t.Fatalf("pasted entity %d is missing", id)
+		}
+
+		if objects[id] {
+			t.Errorf("pasted entity has used id %d", id)
+		}
+		objects[id] = true
+
+		want := float64(128 * (n/2 + 1))
+
+		if origin, ok := e.Origin(); ok {
+			if origin != (Vector3d{32 + want, 32, 65}) {
+				t.Errorf("copy %d of %s is at %v", n/2+1, e.Classname, origin)
+			}
+		}
+
+		for i := range e.Solids {
+			if objects[e.Solids[i].Id] || e.Solids[i].Id == 4 {
+				t.Errorf("pasted brush entity solid has used id %d", e.Solids[i].Id)
+			}
+			objects[e.Solids[i].Id] = true
+
+			if mins, _ := boundsOfSolid(&e.Solids[i]); mins.X != want {
+				t.Errorf("copy %d of %s starts at x %v, want %v", n/2+1, e.Classname, mins.X, want)
+			}
+		}
+	}
+
+	// What was copied is left alone
+	if origin, _ := entities[0].Origin(); origin != (Vector3d{32, 32, 65}) {
+		t.Errorf("copied entity moved to %v", origin)
+	}
+
+	if len(w.Selection().Solids()) != 3 || len(w.Selection().Entities()) != 6 {
+		t.Errorf("selection is %v and %v, want the pasted copies", w.Selection().Solids(), w.Selection().Entities())
+	}
+
+	// It is all one action
+	w.Undo()
+
+	if len(w.Solids()) != 1 || len(w.Entities()) != 0 {
+		t.Errorf("undo left %d solids and %d entities", len(w.Solids()), len(w.Entities()))
+	}
+
+	if len(w.Selection().Solids()) != 0 || len(w.Selection().Entities()) != 0 {
+		t.Errorf("undone copies are still selected")
+	}
+}
+
+func TestPasteInPlace(t *testing.T) {
+	w := newTestWorld(t, cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64}))
+
+	solidIds, _ := w.Paste([]Solid{*w.Solid(1)}, nil, PasteOptions{})
+
+	if len(solidIds) != 1 || solidIds[0] == 1 {
+		t.Fatalf("pasted %v", solidIds)
+	}
+
+	if mins, maxs := boundsOfSolid(w.Solid(solidIds[0])); mins != (Vector3d{0, 0, 0}) || maxs != (Vector3d{64, 64, 64}) {
+		t.Errorf("copy is at %v %v, want in place", mins, maxs)
+	}
+}
+
+func boundsOfSolid(s *Solid) (mins, maxs Vector3d) {
+	b := s.Bounds()
+	return NewVector3dFrom(&b.Min), NewVector3dFrom(&b.Max)
+}
@@ -0,0 +1,240 @@
+package world
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/emily33901/forgery/core/filesystem"
+	"github.com/emily33901/forgery/core/materials"
+)
+
+// MatchMode is how ReplaceMaterials matches material names.
+// Matching is not case sensitive.
+type MatchMode int
+
+const (
+	// MatchExact replaces materials that are Find
+	MatchExact MatchMode = iota
+	// MatchPrefix replaces the start of materials that start with Find
+	MatchPrefix
+	// MatchGlob replaces materials that match the glob Find
+	MatchGlob
+	// MatchRegex replaces whatever matches the regular expression Find.
+	// Replace can use $1 and so on.
+	MatchRegex
+)
+
+// ReplaceOptions are the settings for ReplaceMaterials
+type ReplaceOptions struct {
+	Find    string
+	Replace string
+	Mode    MatchMode
+
+	// Only change selected solids and faces
+	SelectionOnly bool
+	// Only change solids in this visgroup, 0 for any
+	VisgroupId int
+
+	// Change texture scales so that the new material covers each face
+	// the same way as the old one when their sizes are different
+	Rescale bool
+}
+
+// ReplaceMaterials changes the material of every face matching opts as a
+// single undoable action and returns how many faces were changed.
+// fs is used to check that the replacements exist and to find the size
+// of materials when rescaling. Nothing is changed if a replacement
+// does not exist.
+func (w *World) ReplaceMaterials(opts ReplaceOptions, fs *filesystem.Filesystem) (int, error) {
+	replace, err := newMaterialReplacer(&opts)
+	if err != nil {
+		return 0, err
+	}
+
+	// New material for each side of each solid that is changing
+	solids := map[int]map[int]string{}
+	order := []int{}
+	replacements := map[string]bool{}
+
+	for _, id := range w.solidsWhere(func(s *Solid) bool { return w.replaceSolidInScope(s, &opts) }) {
+		sides := map[int]string{}
+
+		for _, side := range w.Solid(id).Sides {
+			if opts.SelectionOnly && !w.selection.SolidSelected(id) && !w.selection.SideSelected(side.Id) {
+				continue
+			}
+
+			if material, ok := replace(side.Material); ok && material != side.Material {
+				sides[side.Id] = material
+			}
+		}
+
+		if len(sides) == 0 {
+			continue
+		}
+
+		for _, material := range sides {
+			replacements[material] = true
+		}
+
+		solids[id] = sides
+		order = append(order, id)
+	}
+
+	if fs != nil {
+		for material := range replacements {
+			if !materials.Exists(material, fs) {
+				return 0, fmt.Errorf("material %s does not exist", material)
+			}
+		}
+	}
+
+	changed := 0
+
+	w.Begin("Replace materials")
+
+	for _, id := range order {
+		sides := solids[id]
+		changed += len(sides)
+
+		w.ModifySolid("Replace materials", id, func(s *Solid) {
+			for i := range s.Sides {
+				side := &s.Sides[i]
+
+				material, ok := sides[side.Id]
+				if !ok {
+					continue
+				}
+
+				if opts.Rescale {
+					rescaleSide(side, material, fs)
+				}
+
+				side.Material = material
+			}
+		})
+	}
+
+	w.Commit()
+
+	return changed, nil
+}
+
+func (w *World) replaceSolidInScope(s *Solid, opts *ReplaceOptions) bool {
+	if opts.VisgroupId != 0 {
+		if s.Editor == nil {
+			return false
+		}
+
+		found := false
+		for _, id := range s.Editor.VisgroupIds {
+			found = found || id == opts.VisgroupId
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if !opts.SelectionOnly || w.selection.SolidSelected(s.Id) {
+		return true
+	}
+
+	for _, side := range s.Sides {
+		if w.selection.SideSelected(side.Id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newMaterialReplacer returns a function that returns the replacement
+// for a material and whether the material matched at all
+func newMaterialReplacer(opts *ReplaceOptions) (func(material string) (string, bool), error) {
+	find := strings.ToLower(opts.Find)
+
+	switch opts.Mode {
+	case MatchExact:
+		return func(material string) (string, bool) {
+			return opts.Replace, strings.ToLower(material) == find
+		}, nil
+
+	case MatchPrefix:
+		return func(material string) (string, bool) {
+			if !strings.HasPrefix(strings.ToLower(material), find) {
+				return "", false
+			}
+
+			return opts.Replace + material[len(find):], true
+		}, nil
+
+	case MatchGlob:
+		if _, err := path.Match(find, ""); err != nil {
+			return nil, err
+		}
+
+		return func(material string) (string, bool) {
+			matched, _ := path.Match(find, strings.ToLower(material))
+			return opts.Replace, matched
+		}, nil
+
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + opts.Find)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(material string) (string, bool) {
+			if !re.MatchString(material) {
+				return "", false
+			}
+
+			return re.ReplaceAllString(material, opts.Replace), true
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown match mode %d", opts.Mode)
+}
+
+// rescaleSide changes the texture scale and shift of side so that
+// material will cover it the same way its current material does
+func rescaleSide(side *Side, material string, fs *filesystem.Filesystem) {
+	oldWidth, oldHeight, ok := materialSize(side.Material, fs)
+	if !ok {
+		return
+	}
+
+	newWidth, newHeight, ok := materialSize(material, fs)
+	if !ok {
+		return
+	}
+
+	rescaleAxis(&side.UAxis, float32(oldWidth)/float32(newWidth))
+	rescaleAxis(&side.VAxis, float32(oldHeight)/float32(newHeight))
+}
+
+// rescaleAxis scales a texture axis by ratio (old size / new size).
+// Shift is in texels so it goes the other way.
+func rescaleAxis(axis *UVTransform, ratio float32) {
+	axis.Scale *= ratio
+	axis.Transform.W /= ratio
+}
+
+// materialSize returns the size in texels of material
+func materialSize(material string, fs *filesystem.Filesystem) (width, height int, ok bool) {
+	if fs == nil {
+		return 0, 0, false
+	}
+
+	mat, err := materials.Load(material, fs)
+	if err != nil || mat.Textures.Albedo == nil {
+		return 0, 0, false
+	}
+
+	width, height = mat.Width(), mat.Height()
+
+	return width, height, width > 0 && height > 0
+}
@@ -0,0 +1,235 @@
+package world
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emily33901/forgery/core/filesystem"
+	"github.com/g3n/engine/math32"
+)
+
+func TestReplaceMaterialsMissing(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "materials", "dev"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "materials", "dev", "dev_measuregeneric01.vmt"), []byte("LightmappedGeneric\n{\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := filesystem.NewFilesystem()
+	fs.RegisterLocalDirectory(dir)
+
+	w := newTestWorld(t, cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64}))
+
+	changed, err := w.ReplaceMaterials(ReplaceOptions{Find: "TOOLS/TOOLSNODRAW", Replace: "DEV/DEV_MISSING"}, fs)
+	if err == nil || changed != 0 {
+		t.Fatalf("replacing with a missing material changed %d faces and returned %v", changed, err)
+	}
+
+	if _, ok := w.history.CanUndo(); ok {
+		t.Error("a failed replace left something to undo")
+	}
+
+	for _, side := range w.Solid(1).Sides {
+		if side.Material != "TOOLS/TOOLSNODRAW" {
+			t.Errorf("side %d was changed to %s", side.Id, side.Material)
+		}
+	}
+
+	changed, err = w.ReplaceMaterials(ReplaceOptions{Find: "TOOLS/TOOLSNODRAW", Replace: "DEV/DEV_MEASUREGENERIC01"}, fs)
+	if err != nil || changed != 6 {
+		t.Fatalf("replace changed %d faces and returned %v, want 6", changed, err)
+	}
+}
+
+func TestReplaceMatchModes(t *testing.T) {
+	materials := []string{
+		"DEV/DEV_MEASUREWALL01A",
+		"DEV/DEV_MEASUREGENERIC01",
+		"TOOLS/TOOLSNODRAW",
+		"Concrete/Wall01",
+		"CONCRETE/FLOOR02",
+		"TOOLS/TOOLSSKIP",
+	}
+
+	tests := []struct {
+		name string
+		opts ReplaceOptions
+		want []string
+	}{
+		{
+			"exact",
+			ReplaceOptions{Find: "tools/toolsnodraw", Replace: "TOOLS/TOOLSCLIP", Mode: MatchExact},
+			[]string{"DEV/DEV_MEASUREWALL01A", "DEV/DEV_MEASUREGENERIC01", "TOOLS/TOOLSCLIP", "Concrete/Wall01", "CONCRETE/FLOOR02", "TOOLS/TOOLSSKIP"},
+		},
+		{
+			"prefix",
+			ReplaceOptions{Find: "dev/dev_", Replace: "DEV/REFLECTIVITY_", Mode: MatchPrefix},
+			[]string{"DEV/REFLECTIVITY_MEASUREWALL01A", "DEV/REFLECTIVITY_MEASUREGENERIC01", "TOOLS/TOOLSNODRAW", "Concrete/Wall01", "CONCRETE/FLOOR02", "TOOLS/TOOLSSKIP"},
+		},
+		{
+			"glob",
+			ReplaceOptions{Find: "tools/*", Replace: "TOOLS/TOOLSCLIP", Mode: MatchGlob},
+			[]string{"DEV/DEV_MEASUREWALL01A", "DEV/DEV_MEASUREGENERIC01", "TOOLS/TOOLSCLIP", "Concrete/Wall01", "CONCRETE/FLOOR02", "TOOLS/TOOLSCLIP"},
+		},
+		{
+			"regex",
+			ReplaceOptions{Find: "^concrete/(.*)$", Replace: "BRICK/$1", Mode: MatchRegex},
+			[]string{"DEV/DEV_MEASUREWALL01A", "DEV/DEV_MEASUREGENERIC01", "TOOLS/TOOLSNODRAW", "BRICK/Wall01", "BRICK/FLOOR02", "TOOLS/TOOLSSKIP"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := cube(1, 1, Vector3d{0, 0, 0}, Vector3d{64, 64, 64})
+			for i := range s.Sides {
+				s.Sides[i].Material = materials[i]
+			}
+
+			w := newTestWorld(t, s)
+
+			want := 0
+			for i := range materials {
+				if materials[i] != test.want[i] {
+					want++
+				}
+			}
+
+			// Without a filesystem replacements are not checked
+			changed, err := w.ReplaceMaterials(test.opts, nil)
+			if err != nil || changed != want {
+				t.Fatalf("replace changed %d faces and returned %v, want %d", changed, err, want)
+			}
+
+			for i, side := range w.Solid(1).Sides {
+				if side.Material != test.want[i] {
+					t.Errorf("side %d is %s, want %s", side.Id, side.Material, test.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := newMaterialReplacer(&ReplaceOptions{Find: "(", Mode: MatchRegex}); err == nil {
+		t.Error("a bad regex was accepted")
+	}
+
+	if _, err := newMaterialReplacer(&ReplaceOptions{Find: "[", Mode: MatchGlob}); err == nil {
+		t.Error("a bad glob was accepted")
+	}
+}
+
+// materialsOf returns the material of every side of every solid in w
+func materialsOf(w *World) map[int]string {
+	ret := map[int]string{}
+
+	for _, s := range w.Solids() {
+		for _, side := range s.Sides {
+			ret[side.Id] = side.Material
+		}
+	}
+
+	return ret
+}
+
+func TestReplaceScope(t *testing.T) {
+	// Every test gets its own copy as the world keeps the solids it is given
+	solids := func() []Solid {
+		solids := rowOfCubes(3)
+		solids[1].Editor = &Editor{VisgroupIds: []int{4, 5}}
+		solids[2].Editor = &Editor{VisgroupIds: []int{4}}
+
+		return solids
+	}
+
+	opts := ReplaceOptions{Find: "TOOLS/TOOLSNODRAW", Replace: "TOOLS/TOOLSCLIP"}
+
+	t.Run("selection only", func(t *testing.T) {
+		w := newTestWorld(t, solids()...)
+
+		// All of solid 1 and one face of solid 3
+		w.Selection().SelectSolids(SelectReplace, 1)
+		w.Selection().SelectSides(SelectAdd, 14)
+
+		opts := opts
+		opts.SelectionOnly = true
+
+		changed, err := w.ReplaceMaterials(opts, nil)
+		if err != nil || changed != 7 {
+			t.Fatalf("replace changed %d faces and returned %v, want 7", changed, err)
+		}
+
+		for id, material := range materialsOf(w) {
+			if clipped := id <= 6 || id == 14; clipped != (material == "TOOLS/TOOLSCLIP") {
+				t.Errorf("side %d is %s", id, material)
+			}
+		}
+	})
+
+	t.Run("visgroup", func(t *testing.T) {
+		w := newTestWorld(t, solids()...)
+
+		opts := opts
+		opts.VisgroupId = 5
+
+		changed, err := w.ReplaceMaterials(opts, nil)
+		if err != nil || changed != 6 {
+			t.Fatalf("replace changed %d faces and returned %v, want 6", changed, err)
+		}
+
+		for id, material := range materialsOf(w) {
+			if clipped := id >= 7 && id <= 12; clipped != (material == "TOOLS/TOOLSCLIP") {
+				t.Errorf("side %d is %s", id, material)
+			}
+		}
+	})
+
+	t.Run("undo", func(t *testing.T) {
+		w := newTestWorld(t, solids()...)
+
+		changed, err := w.ReplaceMaterials(opts, nil)
+		if err != nil || changed != 18 {
+			t.Fatalf("replace changed %d faces and returned %v, want 18", changed, err)
+		}
+
+		// Every solid is changed in one action
+		if !w.Undo() {
+			t.Fatal("nothing to undo")
+		}
+
+		if _, ok := w.history.CanUndo(); ok {
+			t.Error("replace was more than one action")
+		}
+
+		for id, material := range materialsOf(w) {
+			if material != "TOOLS/TOOLSNODRAW" {
+				t.Errorf("side %d is still %s", id, material)
+			}
+		}
+	})
+}
+
+func TestRescaleAxis(t *testing.T) {
+	// A 256 texel material at 0.25 with a 32 texel shift
+	// swapped for a 512 texel one
+	axis := UVTransform{math32.Vector4{1, 0, 0, 32}, 0.25}
+
+	rescaleAxis(&axis, 256.0/512.0)
+
+	if axis.Scale != 0.125 || axis.Transform.W != 64 {
+		t.Errorf("axis is scale %v shift %v, want 0.125 and 64", axis.Scale, axis.Transform.W)
+	}
+
+	if axis.Transform.X != 1 || axis.Transform.Y != 0 || axis.Transform.Z != 0 {
+		t.Errorf("axis direction changed to %v", axis.Transform)
+	}
+
+	// A point 64 units along is 256 texels into the old material
+	// so should be 512 texels into the new one
+	if texel := 64/axis.Scale + axis.Transform.W; texel != 2*(64/0.25+32) {
+		t.Errorf("point maps to texel %v, want %v", texel, 2*(64/0.25+32))
+	}
+}
@@ -0,0 +1,297 @@
+package world
+
+import (
+	"sort"
+
+	"github.com/emily33901/forgery/core/events"
+	"github.com/g3n/engine/math32"
+)
+
+const (
+	// Events
+
+	// SelectionChanged tells other systems that the selection of a world changed
+	SelectionChanged = "Selection.Changed"
+)
+
+type SelectionChangedEvent struct {
+	Selection *Selection
+}
+
+// SelectMode is how a selection operation combines with what is already selected
+type SelectMode int
+
+const (
+	// SelectReplace clears the selection first
+	SelectReplace SelectMode = iota
+	SelectAdd
+	SelectRemove
+	SelectToggle
+)
+
+// Selection is the set of selected solids, faces and entities of a world.
+// There is one per world so every scene window shows the same selection.
+type Selection struct {
+	world *World
+
+	solids   map[int]bool
+	sides    map[int]bool
+	entities map[int]bool
+}
+
+func newSelection(w *World) *Selection {
+	return &Selection{
+		world:    w,
+		solids:   map[int]bool{},
+		sides:    map[int]bool{},
+		entities: map[int]bool{},
+	}
+}
+
+// Selection returns the selection of this world
+func (w *World) Selection() *Selection {
+	return w.selection
+}
+
+func applySelect(set map[int]bool, mode SelectMode, ids []int) bool {
+	changed := false
+
+	for _, id := range ids {
+		selected := set[id]
+
+		switch mode {
+		case SelectReplace, SelectAdd:
+			set[id] = true
+		case SelectRemove:
+			delete(set, id)
+		case SelectToggle:
+			if selected {
+				delete(set, id)
+			} else {
+				set[id] = true
+			}
+		}
+
+		changed = changed || set[id] != selected
+	}
+
+	return changed
+}
+
+func (sel *Selection) clear() bool {
+	changed := len(sel.solids) != 0 || len(sel.sides) != 0 || len(sel.entities) != 0
+
+	sel.solids = map[int]bool{}
+	sel.sides = map[int]bool{}
+	sel.entities = map[int]bool{}
+
+	return changed
+}
+
+func (sel *Selection) changed() {
+	sel.world.selectionDirty = true
+
+	events.Dispatch(SelectionChanged, &SelectionChangedEvent{
+		sel,
+	})
+}
+
+func (sel *Selection) update(set func() map[int]bool, mode SelectMode, ids []int) {
+	if mode != SelectReplace {
+		if applySelect(set(), mode, ids) {
+			sel.changed()
+		}
+
+		return
+	}
+
+	// clear makes new sets so the old ones are left to compare
+	// with, replacing with the same selection is not a change
+	solids, sides, entities := sel.solids, sel.sides, sel.entities
+
+	sel.clear()
+	applySelect(set(), mode, ids)
+
+	if !sameSet(solids, sel.solids) || !sameSet(sides, sel.sides) || !sameSet(entities, sel.entities) {
+		sel.changed()
+	}
+}
+
+func sameSet(a, b map[int]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Clear deselects everything
+func (sel *Selection) Clear() {
+	if sel.clear() {
+		sel.changed()
+	}
+}
+
+// SelectSolids changes the selection of the solids with ids
+func (sel *Selection) SelectSolids(mode SelectMode, ids ...int) {
+	sel.update(func() map[int]bool { return sel.solids }, mode, ids)
+}
+
+// SelectSides changes the selection of the faces with ids
+func (sel *Selection) SelectSides(mode SelectMode, ids ...int) {
+	sel.update(func() map[int]bool { return sel.sides }, mode, ids)
+}
+
+// SelectEntities changes the selection of the entities with ids
+func (sel *Selection) SelectEntities(mode SelectMode, ids ...int) {
+	sel.update(func() map[int]bool { return sel.entities }, mode, ids)
+}
+
+// SelectGroup changes the selection of every solid in the group with id
+func (sel *Selection) SelectGroup(mode SelectMode, id int) {
+	sel.SelectSolids(mode, sel.world.solidsWhere(func(s *Solid) bool {
+		return s.Editor != nil && s.Editor.GroupId == id
+	})...)
+}
+
+// SelectVisgroup changes the selection of every solid in the visgroup with id
+func (sel *Selection) SelectVisgroup(mode SelectMode, id int) {
+	sel.SelectSolids(mode, sel.world.solidsWhere(func(s *Solid) bool {
+		if s.Editor == nil {
+			return false
+		}
+
+		for _, v := range s.Editor.VisgroupIds {
+			if v == id {
+				return true
+			}
+		}
+
+		return false
+	})...)
+}
+
+// SelectMaterial changes the selection of every solid that uses material
+func (sel *Selection) SelectMaterial(mode SelectMode, material string) {
+	sel.SelectSolids(mode, sel.world.solidsWhere(func(s *Solid) bool {
+		for _, side := range s.Sides {
+			if side.Material == material {
+				return true
+			}
+		}
+
+		return false
+	})...)
+}
+
+// SelectMaterialSides changes the selection of every face that uses material
+func (sel *Selection) SelectMaterialSides(mode SelectMode, material string) {
+	ids := []int{}
+
+	for _, s := range sel.world.solids {
+		for _, side := range s.Sides {
+			if side.Material == material {
+				ids = append(ids, side.Id)
+			}
+		}
+	}
+
+	sel.SelectSides(mode, ids...)
+}
+
+// SelectBox changes the selection of every solid that touches box
+func (sel *Selection) SelectBox(mode SelectMode, box math32.Box3) {
+	sel.SelectSolids(mode, sel.world.Spatial().QueryBox(box)...)
+}
+
+func sortedKeys(set map[int]bool) []int {
+	ret := make([]int, 0, len(set))
+
+	for k := range set {
+		ret = append(ret, k)
+	}
+
+	sort.Ints(ret)
+
+	return ret
+}
+
+// Solids returns the ids of the selected solids
+func (sel *Selection) Solids() []int {
+	return sortedKeys(sel.solids)
+}
+
+// Sides returns the ids of the selected faces
+func (sel *Selection) Sides() []int {
+	return sortedKeys(sel.sides)
+}
+
+// Entities returns the ids of the selected entities
+func (sel *Selection) Entities() []int {
+	return sortedKeys(sel.entities)
+}
+
+func (sel *Selection) SolidSelected(id int) bool {
+	return sel.solids[id]
+}
+
+func (sel *Selection) SideSelected(id int) bool {
+	return sel.sides[id]
+}
+
+func (sel *Selection) EntitySelected(id int) bool {
+	return sel.entities[id]
+}
+
+// Empty returns whether nothing is selected
+func (sel *Selection) Empty() bool {
+	return len(sel.solids) == 0 && len(sel.sides) == 0 && len(sel.entities) == 0
+}
+
+// prune deselects solids and faces that no longer exist
+func (sel *Selection) prune() {
+	changed := false
+
+	for id := range sel.solids {
+		if sel.world.Solid(id) == nil {
+			delete(sel.solids, id)
+			changed = true
+		}
+	}
+
+	for id := range sel.sides {
+		if s, _ := sel.world.Side(id); s == nil {
+			delete(sel.sides, id)
+			changed = true
+		}
+	}
+
+	for id := range sel.entities {
+		if sel.world.Entity(id) == nil {
+			delete(sel.entities, id)
+			changed = true
+		}
+	}
+
+	if changed {
+		sel.changed()
+	}
+}
+
+func (w *World) solidsWhere(match func(s *Solid) bool) []int {
+	ids := []int{}
+
+	for i := range w.solids {
+		if match(&w.solids[i]) {
+			ids = append(ids, w.solids[i].Id)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,218 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/emily33901/forgery/core/events"
+	"github.com/g3n/engine/math32"
+)
+
+// rowOfCubes returns n cubes 128 units apart along x with ids from 1
+// and sides numbered from 1, 7, 13 and so on
+func rowOfCubes(n int) []Solid {
+	solids := []Solid{}
+
+	for i := 0; i < n; i++ {
+		offset := float64(i * 128)
+		solids = append(solids, cube(i+1, i*6+1, Vector3d{offset, 0, 0}, Vector3d{offset + 64, 64, 64}))
+	}
+
+	return solids
+}
+
+// countSelectionChanged counts how many times SelectionChanged is sent
+func countSelectionChanged() *int {
+	count := 0
+
+	events.Subscribe(SelectionChanged, func(_ string, _ interface{}) {
+		count++
+	})
+
+	return &count
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestSelectModes(t *testing.T) {
+	tests := []struct {
+		mode SelectMode
+		want []int
+	}{
+		{SelectAdd, []int{1, 2, 3}},
+		{SelectRemove, []int{1}},
+		{SelectToggle, []int{1, 3}},
+		{SelectReplace, []int{2, 3}},
+	}
+
+	for _, test := range tests {
+		w := newTestWorld(t, rowOfCubes(4)...)
+		sel := w.Selection()
+
+		sel.SelectSolids(SelectReplace, 1, 2)
+		sel.SelectSolids(test.mode, 2, 3)
+
+		if got := sel.Solids(); !sameInts(got, test.want) {
+			t.Errorf("solids mode %d selected %v, want %v", test.mode, got, test.want)
+		}
+
+		// Sides 1 and 2 are on solid 1 and 7 is on solid 2
+		sel.SelectSides(SelectReplace, 1, 2)
+		sel.SelectSides(test.mode, 2, 7)
+
+		want := map[int]int{1: 1, 2: 2, 3: 7}
+		wantSides := []int{}
+		for _, id := range test.want {
+			wantSides = append(wantSides, want[id])
+		}
+
+		if got := sel.Sides(); !sameInts(got, wantSides) {
+			t.Errorf("sides mode %d selected %v, want %v", test.mode, got, wantSides)
+		}
+
+		// Replacing sides clears the solids
+		if solids := sel.Solids(); len(solids) != 0 {
+			t.Errorf("replacing sides kept solids %v", solids)
+		}
+	}
+}
+
+func TestSelectionChanged(t *testing.T) {
+	w := newTestWorld(t, rowOfCubes(4)...)
+	sel := w.Selection()
+
+	count := countSelectionChanged()
+
+	sel.SelectSolids(SelectReplace, 1, 2)
+	if *count != 1 {
+		t.Fatalf("selecting sent %d events, want 1", *count)
+	}
+
+	w.selectionDirty = false
+
+	// None of these change what is selected
+	sel.SelectSolids(SelectReplace, 2, 1)
+	sel.SelectSolids(SelectAdd, 1)
+	sel.SelectSolids(SelectRemove, 3)
+	sel.SelectSides(SelectRemove, 1)
+	sel.SelectSolids(SelectToggle)
+
+	if *count != 1 {
+		t.Errorf("selecting the same solids sent %d events", *count-1)
+	}
+
+	if w.selectionDirty {
+		t.Error("selecting the same solids rebuilt the selection")
+	}
+
+	// Replacing with a subset is a change
+	sel.SelectSolids(SelectReplace, 1)
+	if *count != 2 || !w.selectionDirty {
+		t.Errorf("replacing with fewer solids sent %d events", *count-1)
+	}
+
+	// So is replacing with the same ids of a different kind
+	sel.SelectSides(SelectReplace, 1)
+	if *count != 3 {
+		t.Errorf("replacing a solid with a side sent %d events", *count-2)
+	}
+
+	sel.Clear()
+	sel.Clear()
+	if *count != 4 || !sel.Empty() {
+		t.Errorf("clearing sent %d events", *count-3)
+	}
+}
+
+func TestSelectWhere(t *testing.T) {
+	solids := rowOfCubes(4)
+
+	solids[1].Sides[3].Material = "DEV/RED"
+	solids[3].Sides[0].Material = "DEV/RED"
+
+	solids[2].Editor = &Editor{VisgroupIds: []int{5, 6}}
+	solids[3].Editor = &Editor{VisgroupIds: []int{6}}
+
+	w := newTestWorld(t, solids...)
+	sel := w.Selection()
+
+	sel.SelectMaterial(SelectReplace, "DEV/RED")
+	if got := sel.Solids(); !sameInts(got, []int{2, 4}) {
+		t.Errorf("material selected %v", got)
+	}
+
+	sel.SelectMaterialSides(SelectReplace, "DEV/RED")
+	if got := sel.Sides(); !sameInts(got, []int{10, 19}) {
+		t.Errorf("material sides selected %v", got)
+	}
+
+	sel.SelectVisgroup(SelectReplace, 5)
+	if got := sel.Solids(); !sameInts(got, []int{3}) {
+		t.Errorf("visgroup 5 selected %v", got)
+	}
+
+	sel.SelectVisgroup(SelectAdd, 6)
+	if got := sel.Solids(); !sameInts(got, []int{3, 4}) {
+		t.Errorf("adding visgroup 6 selected %v", got)
+	}
+
+	// Touches the first cube and the start of the second
+	sel.SelectBox(SelectReplace, math32.Box3{Min: math32.Vector3{32, 32, 32}, Max: math32.Vector3{150, 40, 40}})
+	if got := sel.Solids(); !sameInts(got, []int{1, 2}) {
+		t.Errorf("box selected %v", got)
+	}
+}
+
+func TestSelectionPrune(t *testing.T) {
+	w := newTestWorld(t, rowOfCubes(4)...)
+	sel := w.Selection()
+
+	sel.SelectSolids(SelectReplace, 2, 3)
+	sel.SelectSides(SelectAdd, 19, 20)
+
+	count := countSelectionChanged()
+
+	w.DeleteSolid(3)
+	w.DeleteSolid(4)
+
+	if got := sel.Solids(); !sameInts(got, []int{2}) {
+		t.Errorf("after deleting selected %v", got)
+	}
+
+	if got := sel.Sides(); len(got) != 0 {
+		t.Errorf("after deleting selected sides %v", got)
+	}
+
+	if *count != 2 {
+		t.Errorf("deleting sent %d selection events, want 2", *count)
+	}
+
+	// Undoing a create removes the new solid from the selection
+	id := w.CreateSolid(cube(0, 0, Vector3d{0, 256, 0}, Vector3d{64, 320, 64}))
+	sel.SelectSolids(SelectAdd, id)
+
+	w.Undo()
+
+	if got := sel.Solids(); !sameInts(got, []int{2}) {
+		t.Errorf("after undoing the create selected %v", got)
+	}
+
+	// Bringing solids back does not select them again
+	w.Undo()
+	w.Undo()
+
+	if got := sel.Solids(); !sameInts(got, []int{2}) {
+		t.Errorf("after undoing the deletes selected %v", got)
+	}
+}
@@ -0,0 +1,138 @@
+package world
+
+import (
+	"math"
+
+	"github.com/g3n/engine/math32"
+)
+
+// Vector3d is a double precision vector used while generating geometry.
+// Results are converted to float32 only when they are uploaded to the gpu.
+type Vector3d struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+// NewVector3dFrom widens a float32 vector
+func NewVector3dFrom(v *math32.Vector3) Vector3d {
+	return Vector3d{float64(v.X), float64(v.Y), float64(v.Z)}
+}
+
+// Vector3 narrows this vector to float32
+func (v Vector3d) Vector3() math32.Vector3 {
+	return math32.Vector3{float32(v.X), float32(v.Y), float32(v.Z)}
+}
+
+func (v Vector3d) Add(o Vector3d) Vector3d {
+	return Vector3d{v.X + o.X, v.Y + o.Y, v.Z + o.Z}
+}
+
+func (v Vector3d) Sub(o Vector3d) Vector3d {
+	return Vector3d{v.X - o.X, v.Y - o.Y, v.Z - o.Z}
+}
+
+func (v Vector3d) Scale(s float64) Vector3d {
+	return Vector3d{v.X * s, v.Y * s, v.Z * s}
+}
+
+func (v Vector3d) Dot(o Vector3d) float64 {
+	return v.X*o.X + v.Y*o.Y + v.Z*o.Z
+}
+
+func (v Vector3d) Cross(o Vector3d) Vector3d {
+	return Vector3d{
+		v.Y*o.Z - v.Z*o.Y,
+		v.Z*o.X - v.X*o.Z,
+		v.X*o.Y - v.Y*o.X,
+	}
+}
+
+func (v Vector3d) LengthSq() float64 {
+	return v.Dot(v)
+}
+
+func (v Vector3d) Length() float64 {
+	return math.Sqrt(v.LengthSq())
+}
+
+// Normalize returns a unit length copy of v or
+// the zero vector if v has no length
+func (v Vector3d) Normalize() Vector3d {
+	l := v.Length()
+	if l == 0 {
+		return Vector3d{}
+	}
+
+	return v.Scale(1 / l)
+}
+
+// ApplyMatrix4 returns v transformed by m
+func (v Vector3d) ApplyMatrix4(m *math32.Matrix4) Vector3d {
+	return Vector3d{
+		float64(m[0])*v.X + float64(m[4])*v.Y + float64(m[8])*v.Z + float64(m[12]),
+		float64(m[1])*v.X + float64(m[5])*v.Y + float64(m[9])*v.Z + float64(m[13]),
+		float64(m[2])*v.X + float64(m[6])*v.Y + float64(m[10])*v.Z + float64(m[14]),
+	}
+}
+
+func (v Vector3d) Component(i int) float64 {
+	switch i {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+func (v *Vector3d) SetComponent(i int, value float64) {
+	switch i {
+	case 0:
+		v.X = value
+	case 1:
+		v.Y = value
+	default:
+		v.Z = value
+	}
+}
+
+// PlaneD is the double precision counterpart of Plane
+type PlaneD struct {
+	Normal Vector3d
+	Dist   float64
+}
+
+// NewPlaneD creates a plane through 3 points in the same way as NewPlane
+func NewPlaneD(a, b, c Vector3d) PlaneD {
+	normal := b.Sub(a).Cross(c.Sub(a)).Normalize()
+
+	return PlaneD{
+		Normal: normal,
+		Dist:   a.Dot(normal),
+	}
+}
+
+// DistanceTo returns the signed distance from the plane to v.
+// Positive is in front.
+func (p *PlaneD) DistanceTo(v Vector3d) float64 {
+	return p.Normal.Dot(v) - p.Dist
+}
+
+// Precise returns the double precision version of this plane.
+// It is built from the original points where possible so that no
+// precision is lost to the float32 normal and distance.
+func (p *Plane) Precise() PlaneD {
+	a, b, c := p.Points[0], p.Points[1], p.Points[2]
+
+	if b.Sub(a).Cross(c.Sub(a)).LengthSq() == 0 {
+		// Plane was not made from points
+		return PlaneD{
+			Normal: NewVector3dFrom(&p.Normal).Normalize(),
+			Dist:   float64(p.Dist),
+		}
+	}
+
+	return NewPlaneD(a, b, c)
+}
@@ -1,25 +1,34 @@
 package world
 
 import (
-	"fmt"
+	"math"
 
 	"github.com/g3n/engine/math32"
 )
 
+// Winding is a convex polygon. Points are kept in double precision
+// so that clipping huge windings down to brush faces does not drift.
 type Winding struct {
-	Points []*math32.Vector3
+	Points []Vector3d
 }
 
 func NewWinding(points int) *Winding {
-	w := &Winding{
-		Points: make([]*math32.Vector3, points),
+	return &Winding{
+		Points: make([]Vector3d, points),
 	}
+}
+
+// Vertices narrows the points of this winding to float32
+// for uploading to the gpu
+func (w *Winding) Vertices() []*math32.Vector3 {
+	ret := make([]*math32.Vector3, len(w.Points))
 
-	for i := range w.Points {
-		w.Points[i] = &math32.Vector3{}
+	for i, p := range w.Points {
+		v := p.Vector3()
+		ret[i] = &v
 	}
 
-	return w
+	return ret
 }
 
 const splitEpsilon = 0.01
@@ -30,7 +39,32 @@ const (
 	splitOn    = 2
 )
 
-func (w *Winding) Clip(split *Plane) {
+// Clone returns a copy of this winding
+func (w *Winding) Clone() *Winding {
+	c := NewWinding(len(w.Points))
+	copy(c.Points, w.Points)
+
+	return c
+}
+
+// Clip removes the part of this winding behind split
+func (w *Winding) Clip(split *PlaneD) {
+	front, _ := w.Split(split)
+
+	if front == nil {
+		// Everything was behind this plane
+		// so we no longer have any points
+		*w = *NewWinding(0)
+		return
+	}
+
+	w.Points = front.Points
+}
+
+// Split cuts this winding in two along split returning the parts in front
+// and behind. Either part is nil if nothing of the winding is on that side.
+// If the whole winding is on the plane it is all returned as the front.
+func (w *Winding) Split(split *PlaneD) (front, back *Winding) {
 	// Figure out which side of the split
 	// each point of this winding is on
 
@@ -46,11 +80,10 @@ func (w *Winding) Clip(split *Plane) {
 	sides := make([]int, windingLength)
 
 	// Distance from the split for each point
-	dists := make([]float32, windingLength)
+	dists := make([]float64, windingLength)
 
 	for i, point := range w.Points {
-		dot := point.Dot(&split.Normal)
-		dot -= split.Dist
+		dot := split.DistanceTo(point)
 
 		dists[i] = dot
 
@@ -70,43 +103,33 @@ func (w *Winding) Clip(split *Plane) {
 
 	if counts[splitFront] == 0 && counts[splitBack] == 0 {
 		// Nothing to split (everything was on the plane)
-		fmt.Println("All on...")
-		return
+		return w.Clone(), nil
 	}
 
 	if counts[splitFront] == 0 {
 		// Everything was behind this plane
-		// so we no longer have any points
-		fmt.Println("All behind...")
-		*w = *NewWinding(0)
-		return
+		return nil, w.Clone()
 	}
 
 	if counts[splitBack] == 0 {
 		// Nothing was behind the split
-		// so nothing to change
-		fmt.Println("All in front...")
-		return
+		return w.Clone(), nil
 	}
 
-	maxPoints := len(w.Points) + 4
-	numPoints := 0
-
-	newWinding := NewWinding(maxPoints)
+	frontPoints := make([]Vector3d, 0, len(w.Points)+4)
+	backPoints := make([]Vector3d, 0, len(w.Points)+4)
 
 	for i, point := range w.Points {
-		mid := newWinding.Points[numPoints]
-
 		if sides[i] == splitOn {
-			*mid = *point
-			numPoints++
+			frontPoints = append(frontPoints, point)
+			backPoints = append(backPoints, point)
 			continue
 		}
 
 		if sides[i] == splitFront {
-			*mid = *point
-			numPoints++
-			mid = newWinding.Points[numPoints]
+			frontPoints = append(frontPoints, point)
+		} else {
+			backPoints = append(backPoints, point)
 		}
 
 		if sides[i+1] == splitOn || sides[i+1] == sides[i] {
@@ -119,9 +142,8 @@ func (w *Winding) Clip(split *Plane) {
 			p2 = w.Points[i+1]
 		}
 
-		numPoints++
-
 		dot := dists[i] / (dists[i] - dists[i+1])
+		mid := Vector3d{}
 
 		for j := 0; j < 3; j++ {
 			// avoid round off error when possible
@@ -135,9 +157,12 @@ func (w *Winding) Clip(split *Plane) {
 				mid.SetComponent(j, comp+dot*(p2.Component(j)-comp))
 			}
 		}
+
+		frontPoints = append(frontPoints, mid)
+		backPoints = append(backPoints, mid)
 	}
 
-	w.Points = newWinding.Points[:numPoints]
+	return &Winding{frontPoints}, &Winding{backPoints}
 }
 
 // Source defines this constant as sqrt(3) * 2 * 16584
@@ -150,57 +175,294 @@ func VectorMAInline(start, direction, dest *math32.Vector3, scale float32) {
 	dest.Z = start.Z + direction.Z*scale
 }
 
-func CreateWindingFromPlane(p *Plane) *Winding {
+func CreateWindingFromPlane(p *PlaneD) *Winding {
 	// https://github.com/emily33901/HammerFromScratch/blob/a0f669718a70632138545fd1a5a493b8299221a0/hammer/brushops.cpp
 
 	// Find the major axis
-	up := &math32.Vector3{}
-
-	normalArray := []float32{p.Normal.X, p.Normal.Y, p.Normal.Z}
-	max := math32.Abs(normalArray[0])
-	idx := 0
+	max := -1.0
+	idx := -1
 
-	for i := 1; i < 3; i++ {
-		v := math32.Abs(normalArray[i])
+	for i := 0; i < 3; i++ {
+		v := math.Abs(p.Normal.Component(i))
 		if v > max {
+			max = v
 			idx = i
 		}
 	}
 
-	if idx == -1 {
-		panic("No major axis found...")
-	}
-
-	if idx == 0 || idx == 1 {
-		up.Z = 1
-	} else {
-		up.X = 1
-	}
+	up := Vector3d{}
 
-	// If X or Y are greater than Z
-	if math32.Abs(p.Normal.X) < math32.Abs(p.Normal.Z) || math32.Abs(p.Normal.Y) < math32.Abs(p.Normal.Z) {
+	switch idx {
+	case 0, 1:
 		up.Z = 1
-	} else {
-		// Z must be the largest
+	case 2:
 		up.X = 1
+	default:
+		panic("No major axis found...")
 	}
 
-	v := up.Dot(&p.Normal)
+	v := up.Dot(p.Normal)
 
-	VectorMAInline(up, &p.Normal, up, -v)
-	up.Normalize()
+	up = up.Sub(p.Normal.Scale(v)).Normalize()
 
-	org := p.Normal.Clone().MultiplyScalar(p.Dist)
+	org := p.Normal.Scale(p.Dist)
 
-	right := up.Clone().Cross(&p.Normal).MultiplyScalar(maxTrace)
-	up = up.MultiplyScalar(maxTrace)
+	right := up.Cross(p.Normal).Scale(maxTrace)
+	up = up.Scale(maxTrace)
 
 	w := NewWinding(4)
 
-	w.Points[0] = org.Clone().Sub(right).Add(up)
-	w.Points[1] = org.Clone().Add(right).Add(up)
-	w.Points[2] = org.Clone().Add(right).Sub(up)
-	w.Points[3] = org.Clone().Sub(right).Sub(up)
+	w.Points[0] = org.Sub(right).Add(up)
+	w.Points[1] = org.Add(right).Add(up)
+	w.Points[2] = org.Add(right).Sub(up)
+	w.Points[3] = org.Sub(right).Sub(up)
 
 	return w
 }
+
+// Area returns the surface area of this winding
+func (w *Winding) Area() float64 {
+	total := 0.0
+
+	for i := 2; i < len(w.Points); i++ {
+		d1 := w.Points[i-1].Sub(w.Points[0])
+		d2 := w.Points[i].Sub(w.Points[0])
+
+		total += d1.Cross(d2).Length()
+	}
+
+	return total * 0.5
+}
+
+// Centre returns the area weighted centre of this winding
+func (w *Winding) Centre() Vector3d {
+	centre := Vector3d{}
+	total := 0.0
+
+	for i := 2; i < len(w.Points); i++ {
+		d1 := w.Points[i-1].Sub(w.Points[0])
+		d2 := w.Points[i].Sub(w.Points[0])
+
+		area := d1.Cross(d2).Length()
+		triCentre := w.Points[0].Add(w.Points[i-1]).Add(w.Points[i]).Scale(1.0 / 3.0)
+
+		centre = centre.Add(triCentre.Scale(area))
+		total += area
+	}
+
+	if total == 0 {
+		// Degenerate so just average the points
+		if len(w.Points) == 0 {
+			return centre
+		}
+
+		for _, p := range w.Points {
+			centre = centre.Add(p)
+		}
+
+		return centre.Scale(1 / float64(len(w.Points)))
+	}
+
+	return centre.Scale(1 / total)
+}
+
+// Plane returns the plane this winding lies on. The normal faces
+// the same way as the plane that the winding was created from.
+func (w *Winding) Plane() PlaneD {
+	// Newells method so that one bad point doesnt throw the normal off
+	normal := Vector3d{}
+
+	for i, a := range w.Points {
+		b := w.Points[(i+1)%len(w.Points)]
+
+		normal.X += (a.Z + b.Z) * (a.Y - b.Y)
+		normal.Y += (a.X + b.X) * (a.Z - b.Z)
+		normal.Z += (a.Y + b.Y) * (a.X - b.X)
+	}
+
+	// Windings are wound clockwise around their plane normal
+	normal = normal.Scale(-1).Normalize()
+
+	return PlaneD{
+		Normal: normal,
+		Dist:   w.Centre().Dot(normal),
+	}
+}
+
+// Bounds returns the smallest and largest component of every point
+func (w *Winding) Bounds() (mins, maxs Vector3d) {
+	mins = Vector3d{math.Inf(1), math.Inf(1), math.Inf(1)}
+	maxs = Vector3d{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+
+	for _, p := range w.Points {
+		mins.X = math.Min(mins.X, p.X)
+		mins.Y = math.Min(mins.Y, p.Y)
+		mins.Z = math.Min(mins.Z, p.Z)
+
+		maxs.X = math.Max(maxs.X, p.X)
+		maxs.Y = math.Max(maxs.Y, p.Y)
+		maxs.Z = math.Max(maxs.Z, p.Z)
+	}
+
+	return
+}
+
+// ContainsPoint checks whether point is inside of (or on the edge of)
+// this winding. Point is expected to lie on the plane of the winding.
+func (w *Winding) ContainsPoint(point Vector3d) bool {
+	if len(w.Points) < 3 {
+		return false
+	}
+
+	normal := w.Plane().Normal
+
+	for i, a := range w.Points {
+		b := w.Points[(i+1)%len(w.Points)]
+
+		// Points inside are always on the same side of each edge
+		edgeNormal := b.Sub(a).Cross(normal).Normalize()
+
+		if point.Sub(a).Dot(edgeNormal) < -splitEpsilon {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RemoveDuplicatePoints removes points which are within splitEpsilon
+// of the next point, wrapping around from the last point to the first
+func (w *Winding) RemoveDuplicatePoints() {
+	points := make([]Vector3d, 0, len(w.Points))
+
+	for i, p := range w.Points {
+		next := w.Points[(i+1)%len(w.Points)]
+
+		if len(w.Points) > 1 && p.Sub(next).Length() < splitEpsilon {
+			continue
+		}
+
+		points = append(points, p)
+	}
+
+	w.Points = points
+}
+
+// RemoveColinearPoints removes points which lie on the
+// straight line between their neighbours
+func (w *Winding) RemoveColinearPoints() {
+	// Taken from qbsp's RemoveColinearPoints
+	points := make([]Vector3d, 0, len(w.Points))
+
+	for i, p := range w.Points {
+		next := w.Points[(i+1)%len(w.Points)]
+		prev := w.Points[(i+len(w.Points)-1)%len(w.Points)]
+
+		v1 := next.Sub(p).Normalize()
+		v2 := p.Sub(prev).Normalize()
+
+		if v1.Dot(v2) < 0.999 {
+			points = append(points, p)
+		}
+	}
+
+	w.Points = points
+}
+
+// Merge joins w and other into a single winding if they are coplanar,
+// share an edge and the result would still be convex.
+// Otherwise it returns nil.
+func (w *Winding) Merge(other *Winding) *Winding {
+	// Taken from qbsp's TryMergeWinding
+	const continuousEpsilon = 0.005
+
+	n1 := len(w.Points)
+	n2 := len(other.Points)
+
+	if n1 < 3 || n2 < 3 {
+		return nil
+	}
+
+	plane := w.Plane()
+	otherPlane := other.Plane()
+
+	if plane.Normal.Dot(otherPlane.Normal) < 0.999 || math.Abs(plane.Dist-otherPlane.Dist) > splitEpsilon {
+		// Not coplanar
+		return nil
+	}
+
+	same := func(a, b Vector3d) bool {
+		return a.Sub(b).Length() < splitEpsilon
+	}
+
+	// Find a shared edge, they will be opposite ways round
+	i, j := -1, -1
+
+find:
+	for a := 0; a < n1; a++ {
+		p1 := w.Points[a]
+		p2 := w.Points[(a+1)%n1]
+
+		for b := 0; b < n2; b++ {
+			p3 := other.Points[b]
+			p4 := other.Points[(b+1)%n2]
+
+			if same(p1, p4) && same(p2, p3) {
+				i, j = a, b
+				break find
+			}
+		}
+	}
+
+	if i == -1 {
+		// No shared edge
+		return nil
+	}
+
+	p1 := w.Points[i]
+	p2 := w.Points[(i+1)%n1]
+
+	// Check that the joins at both ends of the shared edge are convex
+	// and whether the points there are still needed
+	back := w.Points[(i+n1-1)%n1]
+	edgeNormal := plane.Normal.Cross(p1.Sub(back)).Normalize()
+
+	back = other.Points[(j+2)%n2]
+	dot := back.Sub(p1).Dot(edgeNormal)
+	if dot > continuousEpsilon {
+		return nil
+	}
+
+	keep1 := dot < -continuousEpsilon
+
+	back = w.Points[(i+2)%n1]
+	edgeNormal = plane.Normal.Cross(back.Sub(p2)).Normalize()
+
+	back = other.Points[(j+n2-1)%n2]
+	dot = back.Sub(p2).Dot(edgeNormal)
+	if dot > continuousEpsilon {
+		return nil
+	}
+
+	keep2 := dot < -continuousEpsilon
+
+	merged := NewWinding(0)
+
+	for k := (i + 1) % n1; k != i; k = (k + 1) % n1 {
+		if k == (i+1)%n1 && !keep2 {
+			continue
+		}
+
+		merged.Points = append(merged.Points, w.Points[k])
+	}
+
+	for l := (j + 1) % n2; l != j; l = (l + 1) % n2 {
+		if l == (j+1)%n2 && !keep1 {
+			continue
+		}
+
+		merged.Points = append(merged.Points, other.Points[l])
+	}
+
+	return merged
+}
@@ -0,0 +1,537 @@
+package world
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// planeString formats 3 points in the way vmf planes are written
+func planeString(a, b, c Vector3d) string {
+	return fmt.Sprintf("(%v %v %v) (%v %v %v) (%v %v %v)", a.X, a.Y, a.Z, b.X, b.Y, b.Z, c.X, c.Y, c.Z)
+}
+
+// boxPlanes returns the planes of an axis aligned box in the order
+// top, bottom, left, right, back, front with the points hammer uses
+func boxPlanes(mins, maxs Vector3d) []string {
+	return []string{
+		planeString(Vector3d{mins.X, maxs.Y, maxs.Z}, Vector3d{maxs.X, maxs.Y, maxs.Z}, Vector3d{maxs.X, mins.Y, maxs.Z}),
+		planeString(Vector3d{mins.X, mins.Y, mins.Z}, Vector3d{maxs.X, mins.Y, mins.Z}, Vector3d{maxs.X, maxs.Y, mins.Z}),
+		planeString(Vector3d{mins.X, maxs.Y, maxs.Z}, Vector3d{mins.X, mins.Y, maxs.Z}, Vector3d{mins.X, mins.Y, mins.Z}),
+		planeString(Vector3d{maxs.X, maxs.Y, mins.Z}, Vector3d{maxs.X, mins.Y, mins.Z}, Vector3d{maxs.X, mins.Y, maxs.Z}),
+		planeString(Vector3d{maxs.X, maxs.Y, maxs.Z}, Vector3d{mins.X, maxs.Y, maxs.Z}, Vector3d{mins.X, maxs.Y, mins.Z}),
+		planeString(Vector3d{maxs.X, mins.Y, mins.Z}, Vector3d{mins.X, mins.Y, mins.Z}, Vector3d{mins.X, mins.Y, maxs.Z}),
+	}
+}
+
+func solidFromPlanes(planes []string) *Solid {
+	sides := make([]Side, len(planes))
+
+	for i, p := range planes {
+		sides[i] = Side{Id: i + 1, Plane: *NewPlaneFromString(p), Material: "TOOLS/TOOLSNODRAW"}
+	}
+
+	return NewSolid(1, sides, nil)
+}
+
+func TestNewPlaneFromStringPrecision(t *testing.T) {
+	p := NewPlaneFromString("(-16383.123456789 0.1 0.2) (16383.987654321 0.1 0.2) (16383.987654321 -0.3 0.2)")
+
+	want := [3]Vector3d{
+		{-16383.123456789, 0.1, 0.2},
+		{16383.987654321, 0.1, 0.2},
+		{16383.987654321, -0.3, 0.2},
+	}
+
+	if p.Points != want {
+		t.Errorf("points = %v, want %v", p.Points, want)
+	}
+
+	precise := p.Precise()
+	if precise.Normal != (Vector3d{0, 0, -1}) || math.Abs(precise.Dist+0.2) > 1e-12 {
+		t.Errorf("plane = %v, want normal (0 0 -1) and dist -0.2", precise)
+	}
+}
+
+func TestSolidWindings(t *testing.T) {
+	// Shallow ramp that rises 1 unit over 1024
+	ramp := boxPlanes(Vector3d{0, 0, 0}, Vector3d{1024, 64, 64})
+	ramp[0] = planeString(Vector3d{0, 64, 64}, Vector3d{1024, 64, 65}, Vector3d{1024, 0, 65})
+
+	// 1 unit thick slab on two nearly parallel planes that are
+	// nearly parallel to the world axes too
+	slab := boxPlanes(Vector3d{0, 0, -16}, Vector3d{4096, 64, 32})
+	slab[0] = planeString(Vector3d{0, 64, 1}, Vector3d{4096, 64, 5.096}, Vector3d{4096, 0, 5.096})
+	slab[1] = planeString(Vector3d{0, 0, 0}, Vector3d{4096, 0, 4.096}, Vector3d{4096, 64, 4.096})
+
+	slope := math.Sqrt(4096*4096 + 4.096*4.096)
+
+	// Corners of each face of a 128x64x32 box turned 30 degrees
+	// about z and then 45 degrees about x, in the order hammer
+	// writes them so the first 3 make the plane
+	rotated := [][]Vector3d{
+		{{-71.425626, -14.345208, 8.282209}, {39.425626, 30.909626, 53.537043}, {71.425626, -8.282209, 14.345208}, {-39.425626, -53.537043, -30.909626}},
+		{{-39.425626, -30.909626, -53.537043}, {71.425626, 14.345208, -8.282209}, {39.425626, 53.537043, 30.909626}, {-71.425626, 8.282209, -14.345208}},
+		{{-71.425626, -14.345208, 8.282209}, {-39.425626, -53.537043, -30.909626}, {-39.425626, -30.909626, -53.537043}, {-71.425626, 8.282209, -14.345208}},
+		{{39.425626, 53.537043, 30.909626}, {71.425626, 14.345208, -8.282209}, {71.425626, -8.282209, 14.345208}, {39.425626, 30.909626, 53.537043}},
+		{{39.425626, 30.909626, 53.537043}, {-71.425626, -14.345208, 8.282209}, {-71.425626, 8.282209, -14.345208}, {39.425626, 53.537043, 30.909626}},
+		{{71.425626, 14.345208, -8.282209}, {-39.425626, -30.909626, -53.537043}, {-39.425626, -53.537043, -30.909626}, {71.425626, -8.282209, 14.345208}},
+	}
+
+	rotatedPlanes := []string{}
+	for _, face := range rotated {
+		rotatedPlanes = append(rotatedPlanes, planeString(face[0], face[1], face[2]))
+	}
+
+	// Pushed up against the corner of the map
+	edgeMins := Vector3d{16256, -16384, -16384}
+	edgeMaxs := Vector3d{16384, -16256, 16384}
+
+	tests := []struct {
+		name   string
+		planes []string
+		// Expected area of each face in the same order as the planes
+		areas []float64
+		// Expected corners of each face if set, in any order
+		vertices [][]Vector3d
+	}{
+		{
+			"cube",
+			boxPlanes(Vector3d{-64, -64, 0}, Vector3d{64, 64, 64}),
+			[]float64{128 * 128, 128 * 128, 128 * 64, 128 * 64, 128 * 64, 128 * 64},
+			nil,
+		},
+		{
+			"map sized",
+			boxPlanes(Vector3d{-16384, -16384, -16384}, Vector3d{16384, 16384, 16384}),
+			[]float64{32768 * 32768, 32768 * 32768, 32768 * 32768, 32768 * 32768, 32768 * 32768, 32768 * 32768},
+			nil,
+		},
+		{
+			"small and far from the origin",
+			boxPlanes(Vector3d{15000, -15000, 15000}, Vector3d{15008, -14992, 15000.5}),
+			[]float64{64, 64, 4, 4, 4, 4},
+			nil,
+		},
+		{
+			"off grid",
+			boxPlanes(Vector3d{-0.125, -0.25, -0.5}, Vector3d{0.125, 0.25, 0.5}),
+			[]float64{0.125, 0.125, 0.5, 0.5, 0.25, 0.25},
+			nil,
+		},
+		{
+			"shallow ramp",
+			ramp,
+			[]float64{64 * math.Sqrt(1024*1024+1), 1024 * 64, 64 * 64, 65 * 64, 1024 * 64.5, 1024 * 64.5},
+			nil,
+		},
+		{
+			"thin slab",
+			slab,
+			[]float64{64 * slope, 64 * slope, 64, 64, 4096, 4096},
+			nil,
+		},
+		{
+			"rotated",
+			rotatedPlanes,
+			[]float64{128 * 64, 128 * 64, 64 * 32, 64 * 32, 128 * 32, 128 * 32},
+			rotated,
+		},
+		{
+			"map edge",
+			boxPlanes(edgeMins, edgeMaxs),
+			[]float64{128 * 128, 128 * 128, 128 * 32768, 128 * 32768, 128 * 32768, 128 * 32768},
+			[][]Vector3d{
+				{{16256, -16256, 16384}, {16384, -16256, 16384}, {16384, -16384, 16384}, {16256, -16384, 16384}},
+				{{16256, -16384, -16384}, {16384, -16384, -16384}, {16384, -16256, -16384}, {16256, -16256, -16384}},
+				{{16256, -16256, 16384}, {16256, -16384, 16384}, {16256, -16384, -16384}, {16256, -16256, -16384}},
+				{{16384, -16256, -16384}, {16384, -16384, -16384}, {16384, -16384, 16384}, {16384, -16256, 16384}},
+				{{16384, -16256, 16384}, {16256, -16256, 16384}, {16256, -16256, -16384}, {16384, -16256, -16384}},
+				{{16384, -16384, -16384}, {16256, -16384, -16384}, {16256, -16384, 16384}, {16384, -16384, 16384}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := solidFromPlanes(test.planes)
+			windings := s.Windings()
+
+			planes := make([]PlaneD, len(s.Sides))
+			for i := range s.Sides {
+				planes[i] = s.Sides[i].Plane.Precise()
+			}
+
+			for i, w := range windings {
+				if w == nil || len(w.Points) < 3 {
+					t.Errorf("face %d was dropped", i)
+					continue
+				}
+
+				if area := w.Area(); math.Abs(area-test.areas[i]) > test.areas[i]*1e-6 {
+					t.Errorf("face %d has area %v, want %v", i, area, test.areas[i])
+				}
+
+				if test.vertices != nil && !samePoints(w.Points, test.vertices[i], 1e-4) {
+					t.Errorf("face %d has points %v, want %v", i, w.Points, test.vertices[i])
+				}
+
+				for _, p := range w.Points {
+					if d := math.Abs(planes[i].DistanceTo(p)); d > 1e-6 {
+						t.Errorf("face %d has point %v %v off its own plane", i, p, d)
+					}
+
+					// Nothing should stick out of the solid
+					for j := range planes {
+						if d := planes[j].DistanceTo(p); d < -1e-6 {
+							t.Errorf("face %d has point %v %v behind plane %d", i, p, d, j)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// samePoints checks that a and b have the same points
+// to within epsilon, ignoring the order they are in
+func samePoints(a, b []Vector3d, epsilon float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, p := range b {
+		found := false
+
+		for _, q := range a {
+			if math.Abs(p.X-q.X) <= epsilon && math.Abs(p.Y-q.Y) <= epsilon && math.Abs(p.Z-q.Z) <= epsilon {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestClipParallelPlanes(t *testing.T) {
+	top := PlaneD{Vector3d{0, 0, -1}, -64}
+
+	tests := []struct {
+		name  string
+		clip  PlaneD
+		empty bool
+	}{
+		// Facing the same way and on top of each other keeps the winding
+		{"same plane", PlaneD{Vector3d{0, 0, -1}, -64}, false},
+		// Opposite faces of a solid with no thickness
+		{"opposite plane", PlaneD{Vector3d{0, 0, 1}, 64}, false},
+		{"in front", PlaneD{Vector3d{0, 0, -1}, -128}, false},
+		{"behind", PlaneD{Vector3d{0, 0, -1}, 0}, true},
+		{"nearly parallel in front", PlaneD{Vector3d{0.000001, 0, -1}.Normalize(), -64.5}, false},
+		{"nearly parallel behind", PlaneD{Vector3d{0.000001, 0, -1}.Normalize(), -63.5}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := CreateWindingFromPlane(&top)
+			w.Clip(&test.clip)
+
+			if empty := len(w.Points) == 0; empty != test.empty {
+				t.Errorf("winding has %d points, want empty %v", len(w.Points), test.empty)
+			}
+		})
+	}
+}
+
+// square returns a winding on the plane z = height facing up
+// from mins to maxs, wound clockwise like every other winding
+func square(mins, maxs, height float64) *Winding {
+	return &Winding{[]Vector3d{
+		{maxs, maxs, height},
+		{maxs, mins, height},
+		{mins, mins, height},
+		{mins, maxs, height},
+	}}
+}
+
+func TestWindingSplit(t *testing.T) {
+	w := square(0, 64, 0)
+
+	front, back := w.Split(&PlaneD{Vector3d{1, 0, 0}, 16})
+	if front == nil || back == nil {
+		t.Fatalf("split gave front %v and back %v", front, back)
+	}
+
+	if area := front.Area(); area != 48*64 {
+		t.Errorf("front has area %v, want %v", area, 48*64)
+	}
+
+	if area := back.Area(); area != 16*64 {
+		t.Errorf("back has area %v, want %v", area, 16*64)
+	}
+
+	if mins, _ := front.Bounds(); mins.X != 16 {
+		t.Errorf("front starts at %v, want 16", mins.X)
+	}
+
+	if _, maxs := back.Bounds(); maxs.X != 16 {
+		t.Errorf("back ends at %v, want 16", maxs.X)
+	}
+
+	// The original is left alone
+	if len(w.Points) != 4 || w.Area() != 64*64 {
+		t.Errorf("split changed the winding to %v", w.Points)
+	}
+
+	tests := []struct {
+		name        string
+		split       PlaneD
+		front, back bool
+	}{
+		{"all in front", PlaneD{Vector3d{1, 0, 0}, -16}, true, false},
+		{"all behind", PlaneD{Vector3d{1, 0, 0}, 128}, false, true},
+		{"on an edge", PlaneD{Vector3d{1, 0, 0}, 64}, false, true},
+		{"on the plane", PlaneD{Vector3d{0, 0, 1}, 0}, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			front, back := w.Split(&test.split)
+
+			if (front != nil) != test.front || (back != nil) != test.back {
+				t.Errorf("got front %v back %v, want front %v back %v", front != nil, back != nil, test.front, test.back)
+			}
+
+			for _, part := range []*Winding{front, back} {
+				if part != nil && part.Area() != w.Area() {
+					t.Errorf("got area %v, want the whole winding", part.Area())
+				}
+			}
+		})
+	}
+}
+
+func TestWindingSplitCubeFace(t *testing.T) {
+	s := solidFromPlanes(boxPlanes(Vector3d{-64, -64, 0}, Vector3d{64, 64, 64}))
+	top := s.Windings()[0]
+
+	// Cut diagonally across the top face
+	front, back := top.Split(&PlaneD{Vector3d{1, 1, 0}.Normalize(), 0})
+	if front == nil || back == nil {
+		t.Fatalf("split gave front %v and back %v", front, back)
+	}
+
+	if len(front.Points) != 3 || len(back.Points) != 3 {
+		t.Errorf("got %d and %d points, want 2 triangles", len(front.Points), len(back.Points))
+	}
+
+	if math.Abs(front.Area()-back.Area()) > 1e-9 || math.Abs(front.Area()+back.Area()-top.Area()) > 1e-9 {
+		t.Errorf("halves have areas %v and %v of %v", front.Area(), back.Area(), top.Area())
+	}
+
+	// Both halves stay on the face
+	for _, part := range []*Winding{front, back} {
+		plane := part.Plane()
+		if plane.Normal.Sub(Vector3d{0, 0, -1}).Length() > 1e-9 || math.Abs(plane.Dist+64) > 1e-9 {
+			t.Errorf("half is on plane %v, want the top face", plane)
+		}
+	}
+}
+
+func TestWindingClip(t *testing.T) {
+	w := square(0, 64, 0)
+	w.Clip(&PlaneD{Vector3d{0, -1, 0}, -32})
+
+	if mins, maxs := w.Bounds(); mins != (Vector3d{0, 0, 0}) || maxs != (Vector3d{64, 32, 0}) {
+		t.Errorf("clipped to %v %v, want (0 0 0) (64 32 0)", mins, maxs)
+	}
+
+	w.Clip(&PlaneD{Vector3d{0, 1, 0}, 48})
+	if len(w.Points) != 0 {
+		t.Errorf("clipping everything away left %v", w.Points)
+	}
+
+	// Clipping nothing does nothing
+	w.Clip(&PlaneD{Vector3d{0, 1, 0}, 0})
+	if len(w.Points) != 0 {
+		t.Errorf("clipping an empty winding gave %v", w.Points)
+	}
+}
+
+func TestWindingMeasurements(t *testing.T) {
+	w := square(-32, 32, 16)
+
+	if area := w.Area(); area != 64*64 {
+		t.Errorf("area = %v, want %v", area, 64*64)
+	}
+
+	if centre := w.Centre(); centre != (Vector3d{0, 0, 16}) {
+		t.Errorf("centre = %v, want (0 0 16)", centre)
+	}
+
+	if plane := w.Plane(); plane.Normal != (Vector3d{0, 0, 1}) || plane.Dist != 16 {
+		t.Errorf("plane = %v, want normal (0 0 1) and dist 16", plane)
+	}
+
+	if mins, maxs := w.Bounds(); mins != (Vector3d{-32, -32, 16}) || maxs != (Vector3d{32, 32, 16}) {
+		t.Errorf("bounds = %v %v", mins, maxs)
+	}
+
+	// The centre is weighted by area rather than by point
+	triangle := &Winding{[]Vector3d{{0, 0, 0}, {0, 30, 0}, {30, 0, 0}}}
+	triangle.Points = append(triangle.Points[:2], Vector3d{15, 15, 0}, triangle.Points[2])
+
+	if centre := triangle.Centre(); centre.Sub(Vector3d{10, 10, 0}).Length() > 1e-9 {
+		t.Errorf("triangle centre = %v, want (10 10 0)", centre)
+	}
+
+	if area := triangle.Area(); math.Abs(area-450) > 1e-9 {
+		t.Errorf("triangle area = %v, want 450", area)
+	}
+}
+
+func TestWindingContainsPoint(t *testing.T) {
+	w := square(0, 64, 0)
+
+	tests := []struct {
+		point Vector3d
+		want  bool
+	}{
+		{Vector3d{32, 32, 0}, true},
+		{Vector3d{0, 32, 0}, true},
+		{Vector3d{64, 64, 0}, true},
+		{Vector3d{64.001, 32, 0}, true},
+		{Vector3d{65, 32, 0}, false},
+		{Vector3d{-32, -32, 0}, false},
+	}
+
+	for _, test := range tests {
+		if got := w.ContainsPoint(test.point); got != test.want {
+			t.Errorf("ContainsPoint(%v) = %v, want %v", test.point, got, test.want)
+		}
+	}
+}
+
+func TestWindingRemovePoints(t *testing.T) {
+	w := square(0, 64, 0)
+
+	// Point half way along the first edge and a repeat of the last point
+	w.Points = []Vector3d{
+		w.Points[0], {64, 32, 0}, w.Points[1], w.Points[2], w.Points[3], w.Points[3],
+	}
+
+	w.RemoveDuplicatePoints()
+	if len(w.Points) != 5 {
+		t.Errorf("got %d points after removing duplicates, want 5", len(w.Points))
+	}
+
+	w.RemoveColinearPoints()
+	if len(w.Points) != 4 {
+		t.Errorf("got %d points after removing colinear points, want 4", len(w.Points))
+	}
+
+	for _, p := range w.Points {
+		if p == (Vector3d{64, 32, 0}) {
+			t.Errorf("colinear point %v was kept", p)
+		}
+	}
+
+	if w.Area() != 64*64 {
+		t.Errorf("area changed to %v", w.Area())
+	}
+}
+
+func TestWindingMerge(t *testing.T) {
+	a := square(0, 64, 0)
+	b := &Winding{[]Vector3d{{128, 64, 0}, {128, 0, 0}, {64, 0, 0}, {64, 64, 0}}}
+
+	merged := a.Merge(b)
+	if merged == nil {
+		t.Fatal("neighbouring squares did not merge")
+	}
+
+	// The points along the shared edge are no longer needed
+	if len(merged.Points) != 4 {
+		t.Errorf("merged winding has points %v, want 4", merged.Points)
+	}
+
+	if merged.Area() != 2*64*64 {
+		t.Errorf("merged area = %v, want %v", merged.Area(), 2*64*64)
+	}
+
+	if mins, maxs := merged.Bounds(); mins != (Vector3d{0, 0, 0}) || maxs != (Vector3d{128, 64, 0}) {
+		t.Errorf("merged bounds = %v %v", mins, maxs)
+	}
+
+	if plane := merged.Plane(); plane.Normal != (Vector3d{0, 0, 1}) {
+		t.Errorf("merged winding faces %v, want up", plane.Normal)
+	}
+
+	tests := []struct {
+		name  string
+		other *Winding
+	}{
+		{"apart", square(128, 192, 0)},
+		{"touching at a corner", translate(square(64, 128, 0), Vector3d{0, 64, 0})},
+		{"different height", square(64, 128, 16)},
+		{"facing the other way", &Winding{[]Vector3d{{64, 64, 0}, {64, 0, 0}, {128, 0, 0}, {128, 64, 0}}}},
+		{"concave result", &Winding{[]Vector3d{{64, 64, 0}, {128, 128, 0}, {64, 0, 0}}}},
+		{"too few points", &Winding{[]Vector3d{{64, 64, 0}, {64, 0, 0}}}},
+		{"empty", NewWinding(0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if merged := a.Merge(test.other); merged != nil {
+				t.Errorf("merged into %v", merged.Points)
+			}
+		})
+	}
+}
+
+func TestEmptyWinding(t *testing.T) {
+	w := NewWinding(0)
+
+	if w.Area() != 0 {
+		t.Errorf("area = %v, want 0", w.Area())
+	}
+
+	if w.Centre() != (Vector3d{}) {
+		t.Errorf("centre = %v, want zero", w.Centre())
+	}
+
+	if w.ContainsPoint(Vector3d{}) {
+		t.Error("empty winding contains a point")
+	}
+
+	front, back := w.Split(&PlaneD{Vector3d{0, 0, 1}, 0})
+	if front == nil || len(front.Points) != 0 || back != nil {
+		t.Errorf("split of empty winding gave %v %v", front, back)
+	}
+
+	// Every point on one line
+	line := &Winding{[]Vector3d{{0, 0, 0}, {32, 0, 0}, {64, 0, 0}}}
+
+	if line.Area() != 0 {
+		t.Errorf("line area = %v, want 0", line.Area())
+	}
+
+	if line.Centre() != (Vector3d{32, 0, 0}) {
+		t.Errorf("line centre = %v, want (32 0 0)", line.Centre())
+	}
+}
+
+// translate returns a copy of w moved by offset
+func translate(w *Winding, offset Vector3d) *Winding {
+	c := w.Clone()
+	for i := range c.Points {
+		c.Points[i] = c.Points[i].Add(offset)
+	}
+
+	return c
+}
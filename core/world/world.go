@@ -7,7 +7,6 @@ import (
 	"github.com/emily33901/forgery/core/events"
 	"github.com/emily33901/forgery/core/filesystem"
 	"github.com/emily33901/forgery/core/materials"
-	"github.com/emily33901/forgery/core/textures"
 	"github.com/g3n/engine/core"
 	"github.com/g3n/engine/geometry"
 	"github.com/g3n/engine/gls"
@@ -22,10 +21,14 @@ import (
 type World struct {
 	Root *core.Node
 
-	// Always keep a copy of solid and wireframe
-	// because we need to raytrace against the solid one
+	// Every presentation is kept so that scene windows
+	// can switch between them without rebuilding
 	SceneSolid     *core.Node
+	SceneFlat      *core.Node
 	SceneWireframe *core.Node
+
+	// Outlines of the selection drawn over every presentation
+	SceneSelection *core.Node
 	// Debug *core.Node
 
 	// Original vmf file if it exists
@@ -33,104 +36,213 @@ type World struct {
 
 	solids     []Solid
 	sceneDirty bool
+
+	// Entities are not drawn but share ids with solids
+	entities []Entity
+
+	ids          IdAllocator
+	solidsById   map[int]int
+	sidesById    map[int]sideRef
+	entitiesById map[int]int
+
+	history *History
+
+	selection      *Selection
+	selectionDirty bool
+
+	// Scene nodes for each solid so that only the
+	// solids that changed need to be rebuilt
+	solidNodes  map[int]solidNodes
+	dirtySolids map[int]bool
+
+	// Which solids use each material
+	materialSolids map[string]map[int]bool
+	solidMaterials map[int][]string
+
+	// Materials that have loaded or changed since the scene was
+	// last built. Faces using them only need the material swapping.
+	dirtyMaterials map[string]bool
+
+	// Geometry is generated in the background. The latest version queued
+	// for each solid is kept so that stale results can be thrown away.
+	builder       *Builder
+	buildVersions map[int]int
+	buildVersion  int
+
+	// Spatial index over solids, built on first use
+	spatial *BVH
 }
 
 func New(solids []Solid) *World {
+	return NewWithEntities(solids, nil)
+}
+
+// NewWithEntities creates a world from the solids of worldspawn
+// and every other entity in the map
+func NewWithEntities(solids []Solid, entities []Entity) *World {
 	w := &World{}
 
 	w.Root = core.NewNode()
 	w.solids = solids
+	w.entities = entities
+
+	if repaired := w.repairIds(); repaired != 0 {
+		fmt.Println("Repaired", repaired, "missing or duplicate ids")
+	}
+
+	w.reindex()
+	w.history = NewHistory(DefaultHistoryDepth)
+	w.selection = newSelection(w)
 	w.SceneSolid = core.NewNode()
+	w.SceneFlat = core.NewNode()
 	w.SceneWireframe = core.NewNode()
+	w.SceneSelection = core.NewNode()
 	w.sceneDirty = true
 
-	w.Root.Add(w.SceneSolid).Add(w.SceneWireframe)
+	w.solidNodes = map[int]solidNodes{}
+	w.dirtySolids = map[int]bool{}
+	w.materialSolids = map[string]map[int]bool{}
+	w.solidMaterials = map[int][]string{}
+	w.dirtyMaterials = map[string]bool{}
+
+	w.builder = NewBuilder(0)
+	w.buildVersions = map[int]int{}
+
+	w.Root.Add(w.SceneSolid).Add(w.SceneFlat).Add(w.SceneWireframe).Add(w.SceneSelection)
+	w.SetPresentation(PresentationTextured)
 
-	events.Subscribe(textures.TextureLoaded, func(_ string, evdata interface{}) {
-		w.MakeDirty()
+	events.SubscribeID(materials.MaterialLoaded, w, func(_ string, evdata interface{}) {
+		ev := evdata.(*materials.MaterialLoadedEvent)
+		w.MakeMaterialDirty(ev.Path)
+	})
+
+	// Changed on disk so everything using it has to be uploaded again
+	events.SubscribeID(materials.MaterialReloaded, w, func(_ string, evdata interface{}) {
+		ev := evdata.(*materials.MaterialReloadedEvent)
+		w.MakeMaterialDirty(ev.Path)
 	})
 
 	return w
 }
 
+// Close stops the background work of this world.
+// It should not be used afterwards.
+func (w *World) Close() {
+	w.builder.Stop()
+	events.UnsubscribeAllID(w)
+}
+
+// MakeDirty causes the whole scene to be rebuilt
 func (w *World) MakeDirty() {
 	w.sceneDirty = true
 }
 
-func CreateFace(w *Winding, materialName string, fs *filesystem.Filesystem, uaxis, vaxis *UVTransform) (*geometry.Geometry, *material.Standard) {
-	geom := geometry.NewGeometry()
-
-	// Get the verticies
-	verts := math32.NewArrayF32(0, 16)
-
-	flipVertex := func(v ...*math32.Vector3) (ret []*math32.Vector3) {
-		for _, x := range v {
-			ret = append(ret, &math32.Vector3{
-				x.X,
-				x.Z,
-				x.Y,
-			})
+// MakeSolidDirty should be called after the solid with id has been
+// changed, added or removed so that only it gets rebuilt
+func (w *World) MakeSolidDirty(id int) {
+	if w.spatial != nil {
+		if s := w.Solid(id); s != nil {
+			w.spatial.Update(s)
+		} else {
+			w.spatial.Remove(id)
 		}
-		return
 	}
 
-	// flipTransform := func(v ...*UVTransform) (ret []*UVTransform) {
-	// 	for _, x := range v {
-	// 		ret = append(ret, &UVTransform{
-	// 			Transform: math32.Vector4{
-	// 				x.Transform.X,
-	// 				x.Transform.Z,
-	// 				x.Transform.Y,
-	// 				x.Transform.W,
-	// 			},
-	// 			Scale: x.Scale,
-	// 		})
-	// 	}
-	// 	return
-	// }
-
-	// flipVertex := func(v ...*math32.Vector3) (ret []*math32.Vector3) {
-	// 	ret = v
-	// 	return
-	// }
-
-	verts.AppendVector3(
-		flipVertex(w.Points...)...,
-	)
+	w.dirtySolids[id] = true
 
-	// Create a vbo
-	indicies := math32.NewArrayU32(0, verts.Len()*2)
-	i := 0
-	j := 1
-	for j+1 < len(w.Points) {
-		indicies.Append(0)
-		indicies.Append(uint32(j))
-		indicies.Append(uint32(j + 1))
-
-		j += 1
-		i += 3
+	if w.selection.SolidSelected(id) {
+		w.selectionDirty = true
 	}
+}
 
-	// normals
-	normals := math32.NewArrayF32(0, 16)
+// MakeMaterialDirty gives every face that uses material
+// the material again without rebuilding their solids
+func (w *World) MakeMaterialDirty(material string) {
+	w.dirtyMaterials[material] = true
+}
 
-	// Calculate the normal by picking 2 points and crossing them
-	a := flipVertex(w.Points[0])[0]
-	b := flipVertex(w.Points[1])[0].Sub(a)
-	c := flipVertex(w.Points[2])[0].Sub(a)
-	normalVec := b.Cross(c)
+// Solid returns the solid with id or nil if there is none
+func (w *World) Solid(id int) *Solid {
+	if i, ok := w.solidsById[id]; ok {
+		return &w.solids[i]
+	}
+
+	return nil
+}
 
-	for i := 0; i < indicies.Len(); i++ {
-		normals.AppendVector3(normalVec)
+// Solids returns every solid in this world. The pointers are only valid
+// until solids are next added or removed.
+func (w *World) Solids() []*Solid {
+	ret := make([]*Solid, len(w.solids))
+
+	for i := range w.solids {
+		ret[i] = &w.solids[i]
+	}
+
+	return ret
+}
+
+// Spatial returns the spatial index for this world
+func (w *World) Spatial() *BVH {
+	if w.spatial == nil {
+		w.spatial = NewBVH(w.solids)
+	}
+
+	return w.spatial
+}
+
+// Raycast finds the closest face hit by a ray in world space
+func (w *World) Raycast(origin, direction math32.Vector3) (RayHit, bool) {
+	return w.Spatial().Raycast(origin, direction)
+}
+
+// SwapYZ converts between world space (z up) and scene space (y up).
+// It works in both directions.
+func SwapYZ(v math32.Vector3) math32.Vector3 {
+	return math32.Vector3{v.X, v.Z, v.Y}
+}
+
+// CreateFace uploads face to the gpu. This has to happen on the render thread.
+func CreateFace(face *FaceData, fs *filesystem.Filesystem) (*geometry.Geometry, *material.Standard) {
+	geom := geometry.NewGeometry()
+
+	// Get the verticies
+	verts := math32.NewArrayF32(0, len(face.Positions)*3)
+	for i := range face.Positions {
+		verts.AppendVector3(&face.Positions[i])
+	}
+
+	// Create a vbo
+	indicies := math32.NewArrayU32(0, len(face.Indices))
+	indicies.Append(face.Indices...)
+
+	// normals
+	normals := math32.NewArrayF32(0, len(face.Positions)*3)
+	for range face.Positions {
+		normals.AppendVector3(&face.Normal)
 	}
 
 	// uvs
-	sourceMat, err := materials.Load(materialName, fs)
+	sourceMat, err := materials.Load(face.Material, fs)
 
 	if err != nil {
 		panic(err)
 	}
 
+	mat := faceMaterial(sourceMat)
+	uvs := faceUVs(face, sourceMat)
+
+	geom.SetIndices(indicies)
+	geom.AddVBO(gls.NewVBO(verts).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+	geom.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
+	// gls.NewVBO(verts).AddAttrib(gls.VertexTexcoord)
+
+	return geom, mat
+}
+
+// faceMaterial returns the g3n material that faces using sourceMat are drawn with
+func faceMaterial(sourceMat *materials.Material) *material.Standard {
 	// mat := material.NewStandard(&math32.Color{1, 1, 1})
 	mat := sourceMat.G3nMaterial()
 	mat.SetUseLights(material.UseLightAll)
@@ -139,6 +251,11 @@ func CreateFace(w *Winding, materialName string, fs *filesystem.Filesystem, uaxi
 	// all sides face inwards so we want to draw the back face
 	mat.SetSide(material.SideBack)
 
+	return mat
+}
+
+// faceUVs divides the uvs of face by the size of sourceMat
+func faceUVs(face *FaceData, sourceMat *materials.Material) math32.ArrayF32 {
 	width := 128
 	height := 128
 
@@ -147,117 +264,364 @@ func CreateFace(w *Winding, materialName string, fs *filesystem.Filesystem, uaxi
 		height = sourceMat.Height()
 	}
 
-	u := uaxis
-	v := vaxis
+	uvs := math32.NewArrayF32(0, len(face.UVs))
+	for i := 0; i+1 < len(face.UVs); i += 2 {
+		uvs.Append(float32(face.UVs[i]/float64(width)), float32(face.UVs[i+1]/float64(height)))
+	}
 
-	uvs := math32.NewArrayF32(0, 16)
-	for _, vertex := range w.Points {
-		cu := ((u.Transform.X * vertex.X) +
-			(u.Transform.Y * vertex.Y) +
-			(u.Transform.Z * vertex.Z)) / float32(u.Scale) / float32(width)
+	return uvs
+}
 
-		cv := ((v.Transform.X * vertex.X) +
-			(v.Transform.Y * vertex.Y) +
-			(v.Transform.Z * vertex.Z)) / float32(v.Scale) / float32(height)
+// Presentation is how solids are drawn
+type Presentation int
 
-		// uvs.Append(1.0, 1.0)
-		uvs.Append(cu, cv)
-	}
+const (
+	PresentationTextured Presentation = iota
+	PresentationFlat
+	PresentationWireframe
+)
 
-	geom.SetIndices(indicies)
-	geom.AddVBO(gls.NewVBO(verts).AddAttrib(gls.VertexPosition))
-	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
-	geom.AddVBO(gls.NewVBO(uvs).AddAttrib(gls.VertexTexcoord))
-	// gls.NewVBO(verts).AddAttrib(gls.VertexTexcoord)
+// solidNodes are the scene nodes for a single solid
+// in each presentation
+type solidNodes struct {
+	textured  *core.Node
+	flat      *core.Node
+	wireframe *graphic.Lines
+
+	// What was uploaded and the textured mesh of each of its faces
+	// so that materials can be swapped without rebuilding
+	data   *SolidData
+	meshes []*graphic.Mesh
+}
 
-	return geom, mat
+// SetPresentation chooses how the scene will be drawn. Scene windows
+// share a scene so this should be set before each one renders.
+func (w *World) SetPresentation(p Presentation) {
+	w.SceneSolid.SetVisible(p == PresentationTextured)
+	w.SceneFlat.SetVisible(p == PresentationFlat)
+	w.SceneWireframe.SetVisible(p == PresentationWireframe)
 }
 
 // BuildScene converts the internal representation into
-// a g3n scene which can be rendered
+// a g3n scene which can be rendered.
+// Geometry is generated in the background and uploaded
+// by later calls as it becomes ready.
 func (w *World) BuildScene(fs *filesystem.Filesystem) {
-	if w.sceneDirty == false {
-		return
+	if w.sceneDirty {
+		w.builder.Cancel()
+		w.buildVersions = map[int]int{}
+
+		// Cleanup the old scene
+		w.SceneSolid.DisposeChildren(true)
+		w.SceneSolid.SetName("World main node")
+		w.SceneFlat.DisposeChildren(true)
+		w.SceneWireframe.DisposeChildren(true)
+		w.Root.Add(helper.NewAxes(128))
+
+		w.solidNodes = map[int]solidNodes{}
+		w.dirtySolids = map[int]bool{}
+		w.materialSolids = map[string]map[int]bool{}
+		w.solidMaterials = map[int][]string{}
+		w.dirtyMaterials = map[string]bool{}
+
+		// Start building a new scene
+		// This essentially goes through every solid and whatnot
+		// building up nodes out of geometry
+
+		for i := range w.solids {
+			w.queueSolid(&w.solids[i])
+		}
+
+		l1 := light.NewAmbient(&math32.Color{1, 1, 1}, 1.0)
+		w.Root.Add(l1)
+
+		w.sceneDirty = false
+	} else if len(w.dirtySolids) != 0 {
+		// Uploading can load materials which can make more solids dirty
+		dirty := w.dirtySolids
+		w.dirtySolids = map[int]bool{}
+
+		for id := range dirty {
+			if s := w.Solid(id); s != nil {
+				w.queueSolid(s)
+			} else {
+				// Solid was removed
+				delete(w.buildVersions, id)
+				w.removeSolid(id)
+			}
+		}
 	}
 
-	// Cleanup the old scene
-	w.SceneSolid.DisposeChildren(true)
-	w.SceneSolid.SetName("World main node")
-	w.SceneWireframe.DisposeChildren(true)
-	w.Root.Add(helper.NewAxes(128))
+	w.uploadBuiltSolids(fs)
+	w.updateMaterials(fs)
 
-	// Start building a new scene
-	// This essentially goes through every solid and whatnot
-	// building up nodes out of geometry
+	if w.selectionDirty {
+		w.buildSelection()
+	}
+}
 
-	for _, s := range w.solids {
-		solidNode := core.NewNode()
-		w.SceneSolid.Add(solidNode)
+// selectionColor is the colour hammer outlines selected solids with
+var selectionColor = math32.Color{1, 0, 0}
 
-		solidNode.SetLoaderID(strconv.Itoa(s.Id))
+// buildSelection outlines the selected solids.
+// The selection is small so this is done straight away.
+func (w *World) buildSelection() {
+	w.SceneSelection.DisposeChildren(true)
 
-		// https://github.com/emily33901/HammerFromScratch/blob/a0f669718a70632138545fd1a5a493b8299221a0/hammer/mapsolid.cpp#L788
+	for _, id := range w.selection.Solids() {
+		s := w.Solid(id)
+		if s == nil {
+			continue
+		}
 
-		usePlane := make([]bool, len(s.Sides))
+		data := NewSolidData(s)
+		data.Color = selectionColor
 
-		for i, side := range s.Sides {
-			if side.Plane.Normal.LengthSq() == 0 {
-				// Not a valid plane
-				usePlane[i] = false
-				continue
-			}
+		w.SceneSelection.Add(createWireframe(data))
+	}
 
-			usePlane[i] = true
+	// Selected faces are outlined on their own
+	for _, id := range w.sidesBySolid(w.selection.Sides()) {
+		s := w.Solid(id)
+		if s == nil || w.selection.SolidSelected(id) {
+			continue
+		}
 
-			// Check this plane isnt identical to another plane
-			for j, side2 := range s.Sides {
-				if i == j {
-					break
-				}
+		data := NewSolidData(s)
+		data.Color = selectionColor
 
-				if side.Plane.Normal.Dot(&side2.Plane.Normal) > 0.999 && math32.Abs(side.Plane.Dist-side2.Plane.Dist) < 0.1 {
-					usePlane[j] = false
-				}
+		faces := data.Faces[:0]
+		for _, face := range data.Faces {
+			if w.selection.SideSelected(face.SideId) {
+				faces = append(faces, face)
 			}
 		}
+		data.Faces = faces
+
+		w.SceneSelection.Add(createWireframe(data))
+	}
 
-		// Now that we have all of the faces and we know which ones to use
-		// its time to clip all of them to get the points
+	w.selectionDirty = false
+}
 
-		for i, side := range s.Sides {
-			if usePlane[i] == false {
-				// we are not using this plane
-				continue
-			}
+// sidesBySolid returns the ids of the solids that own the sides with ids
+func (w *World) sidesBySolid(ids []int) []int {
+	seen := map[int]bool{}
+	solids := []int{}
+
+	for _, id := range ids {
+		if s, _ := w.Side(id); s != nil && !seen[s.Id] {
+			seen[s.Id] = true
+			solids = append(solids, s.Id)
+		}
+	}
+
+	return solids
+}
+
+// WaitForBuilds blocks until every queued solid is built and uploaded
+func (w *World) WaitForBuilds(fs *filesystem.Filesystem) {
+	w.BuildScene(fs)
+	w.builder.Wait()
+	w.uploadBuiltSolids(fs)
+}
+
+// CancelBuilds throws away any solids that have not been uploaded yet.
+// They keep whatever was in the scene before.
+func (w *World) CancelBuilds() {
+	w.builder.Cancel()
+	w.buildVersions = map[int]int{}
+}
+
+// Building returns the number of solids waiting to be uploaded
+func (w *World) Building() int {
+	return len(w.buildVersions)
+}
+
+func (w *World) queueSolid(s *Solid) {
+	w.buildVersion++
+	w.buildVersions[s.Id] = w.buildVersion
+
+	w.builder.Queue(s, w.buildVersion)
+}
+
+func (w *World) uploadBuiltSolids(fs *filesystem.Filesystem) {
+	results, failed := w.builder.Results()
+
+	for _, err := range failed {
+		if v, ok := w.buildVersions[err.SolidId]; !ok || v != err.version {
+			continue
+		}
+
+		// Keep whatever was in the scene before rather than
+		// taking the whole editor down with one broken solid
+		fmt.Println("Failed", err)
+		delete(w.buildVersions, err.SolidId)
+	}
+
+	for _, data := range results {
+		if v, ok := w.buildVersions[data.Id]; !ok || v != data.version {
+			// Solid has changed since this was queued
+			continue
+		}
+
+		delete(w.buildVersions, data.Id)
+
+		w.removeSolid(data.Id)
+		w.uploadSolid(data, fs)
+	}
+}
+
+func (w *World) removeSolid(id int) {
+	if nodes, ok := w.solidNodes[id]; ok {
+		w.SceneSolid.Remove(nodes.textured)
+		nodes.textured.DisposeChildren(true)
+
+		w.SceneFlat.Remove(nodes.flat)
+		nodes.flat.DisposeChildren(true)
+
+		w.SceneWireframe.Remove(nodes.wireframe)
+		nodes.wireframe.Dispose()
+
+		delete(w.solidNodes, id)
+	}
+
+	for _, m := range w.solidMaterials[id] {
+		delete(w.materialSolids[m], id)
+	}
+
+	delete(w.solidMaterials, id)
+}
+
+func (w *World) uploadSolid(data *SolidData, fs *filesystem.Filesystem) {
+	solidNode := core.NewNode()
+	flatNode := core.NewNode()
+	wireframeNode := createWireframe(data)
+
+	w.SceneSolid.Add(solidNode)
+	w.SceneFlat.Add(flatNode)
+	w.SceneWireframe.Add(wireframeNode)
 
-			winding := CreateWindingFromPlane(&side.Plane)
+	nodes := solidNodes{solidNode, flatNode, wireframeNode, data, nil}
 
-			for j, side := range s.Sides {
-				if j != i && len(winding.Points) > 0 {
-					winding.Clip(&side.Plane)
+	solidNode.SetLoaderID(strconv.Itoa(data.Id))
+	flatNode.SetLoaderID(strconv.Itoa(data.Id))
+	wireframeNode.SetLoaderID(strconv.Itoa(data.Id))
+
+	for i := range data.Faces {
+		face := &data.Faces[i]
+
+		geom, mat := CreateFace(face, fs)
+
+		sideNode := graphic.NewMesh(geom, mat)
+		sideNode.SetVisible(true)
+		sideNode.SetLoaderID(strconv.Itoa(face.SideId))
+
+		solidNode.Add(sideNode)
+		nodes.meshes = append(nodes.meshes, sideNode)
+
+		flatSideNode := createFlatFace(face, data.Color)
+		flatSideNode.SetLoaderID(strconv.Itoa(face.SideId))
+
+		flatNode.Add(flatSideNode)
+
+		if w.materialSolids[face.Material] == nil {
+			w.materialSolids[face.Material] = map[int]bool{}
+		}
+
+		w.materialSolids[face.Material][data.Id] = true
+		w.solidMaterials[data.Id] = append(w.solidMaterials[data.Id], face.Material)
+	}
+
+	w.solidNodes[data.Id] = nodes
+}
+
+// updateMaterials swaps the material of every face using a dirty material
+// and divides their uvs by its size again in case that changed
+func (w *World) updateMaterials(fs *filesystem.Filesystem) {
+	// Getting a material can load it which can make it dirty again
+	dirty := w.dirtyMaterials
+	w.dirtyMaterials = map[string]bool{}
+
+	for name := range dirty {
+		if len(w.materialSolids[name]) == 0 {
+			continue
+		}
+
+		sourceMat, err := materials.Load(name, fs)
+		if err != nil {
+			// Keep what was there before
+			continue
+		}
+
+		mat := faceMaterial(sourceMat)
+
+		for id := range w.materialSolids[name] {
+			nodes := w.solidNodes[id]
+
+			for i := range nodes.data.Faces {
+				face := &nodes.data.Faces[i]
+				if face.Material != name {
+					continue
 				}
-			}
 
-			if len(winding.Points) == 0 {
-				fmt.Println("Empty winding")
-				continue
+				nodes.meshes[i].SetMaterial(mat)
+				nodes.meshes[i].GetGeometry().VBO(gls.VertexTexcoord).SetBuffer(faceUVs(face, sourceMat))
 			}
+		}
+	}
+}
 
-			// This winding has points in it that can be turned into a face
-			geom, mat := CreateFace(winding, side.Material, fs, &side.UAxis, &side.VAxis)
+// createFlatFace creates an untextured version of face in color
+// shaded by which way it is facing
+func createFlatFace(face *FaceData, color math32.Color) *graphic.Mesh {
+	geom := geometry.NewGeometry()
 
-			sideNode := graphic.NewMesh(geom, mat)
-			sideNode.SetVisible(true)
-			sideNode.SetLoaderID(strconv.Itoa(side.Id))
+	verts := math32.NewArrayF32(0, len(face.Positions)*3)
+	normals := math32.NewArrayF32(0, len(face.Positions)*3)
+	for i := range face.Positions {
+		verts.AppendVector3(&face.Positions[i])
+		normals.AppendVector3(&face.Normal)
+	}
 
-			solidNode.Add(sideNode)
-		}
+	indicies := math32.NewArrayU32(0, len(face.Indices))
+	indicies.Append(face.Indices...)
+
+	geom.SetIndices(indicies)
+	geom.AddVBO(gls.NewVBO(verts).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+
+	// Faces pointing up or down are brightest so that
+	// the shape of the solid is still readable
+	normal := face.Normal
+	normal.Normalize()
+	shade := 0.6 + 0.4*math32.Abs(normal.Y)
 
-		// TODO wireframe
+	mat := material.NewStandard(&math32.Color{color.R * shade, color.G * shade, color.B * shade})
+	mat.SetUseLights(material.UseLightAll)
+	mat.SetSide(material.SideBack)
+
+	return graphic.NewMesh(geom, mat)
+}
+
+// createWireframe creates lines around the edge of every face of data
+func createWireframe(data *SolidData) *graphic.Lines {
+	geom := geometry.NewGeometry()
+
+	positions := math32.NewArrayF32(0, 0)
+	colors := math32.NewArrayF32(0, 0)
+
+	for i := range data.Faces {
+		points := data.Faces[i].Positions
+
+		for j := range points {
+			positions.AppendVector3(&points[j], &points[(j+1)%len(points)])
+			colors.AppendColor(&data.Color, &data.Color)
+		}
 	}
 
-	l1 := light.NewAmbient(&math32.Color{1, 1, 1}, 1.0)
-	w.Root.Add(l1)
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(colors).AddAttrib(gls.VertexColor))
 
-	w.sceneDirty = false
+	return graphic.NewLines(geom, material.NewBasic())
 }
@@ -0,0 +1,273 @@
+package forgery
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/emily33901/forgery/core/events"
+	"github.com/emily33901/forgery/core/vmf"
+	"github.com/emily33901/forgery/core/world"
+	"github.com/inkyblackness/imgui-go"
+)
+
+// Defaults for autosaving
+const (
+	DefaultAutosaveInterval = 5 * time.Minute
+	DefaultAutosaveBackups  = 5
+)
+
+// DefaultAutosaveDir returns where backups go if nothing else is configured
+func DefaultAutosaveDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "forgery", "autosave")
+}
+
+// While forgery is running a map this file, named after the map, exists
+// in the autosave directory holding its pid. If it is there on startup
+// and that process is gone forgery did not exit cleanly.
+const runningMarker = ".running"
+
+// autosaver writes the open map to a rotating set of backups
+type autosaver struct {
+	dir      string
+	interval time.Duration
+	backups  int
+
+	// Backups are named after the map
+	name string
+
+	lastSave time.Time
+	dirty    bool
+}
+
+func newAutosaver(dir string, interval time.Duration, backups int, mapPath string) *autosaver {
+	if backups < 1 {
+		backups = 1
+	}
+
+	name := strings.TrimSuffix(filepath.Base(mapPath), filepath.Ext(mapPath))
+	if name == "" || name == "." {
+		name = "untitled"
+	}
+
+	return &autosaver{
+		dir:      dir,
+		interval: interval,
+		backups:  backups,
+		name:     name,
+		lastSave: time.Now(),
+	}
+}
+
+func (a *autosaver) markerPath() string {
+	return filepath.Join(a.dir, a.name+runningMarker)
+}
+
+// uncleanShutdown returns whether the last run with this map did not exit
+// cleanly. Another forgery that is still running it does not count.
+func (a *autosaver) uncleanShutdown() bool {
+	data, err := ioutil.ReadFile(a.markerPath())
+	if err != nil {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		// Something is wrong with it so assume the worst
+		return true
+	}
+
+	return !processRunning(pid)
+}
+
+// processRunning returns whether the process with pid is still running
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	if runtime.GOOS == "windows" {
+		// Finding a process opens it which fails if it has exited
+		p.Release()
+		return true
+	}
+
+	// Finding always works elsewhere, signal 0 checks without sending anything
+	err = p.Signal(syscall.Signal(0))
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// start marks forgery as running this map
+func (a *autosaver) start() error {
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(a.markerPath(), []byte(strconv.Itoa(os.Getpid())), 0644)
+}
+
+// stop marks forgery as having exited cleanly
+func (a *autosaver) stop() {
+	os.Remove(a.markerPath())
+}
+
+func (a *autosaver) backupPath(slot int) string {
+	return filepath.Join(a.dir, fmt.Sprintf("%s.autosave%d.vmf", a.name, slot))
+}
+
+func (a *autosaver) emergencyPath() string {
+	return filepath.Join(a.dir, a.name+".emergency.vmf")
+}
+
+// newestBackup returns the most recent backup of this map
+func (a *autosaver) newestBackup() (path string, modified time.Time, ok bool) {
+	candidates := []string{a.emergencyPath()}
+	for slot := 0; slot < a.backups; slot++ {
+		candidates = append(candidates, a.backupPath(slot))
+	}
+
+	for _, c := range candidates {
+		info, err := os.Stat(c)
+		if err != nil {
+			continue
+		}
+
+		if !ok || info.ModTime().After(modified) {
+			path, modified, ok = c, info.ModTime(), true
+		}
+	}
+
+	return path, modified, ok
+}
+
+// update saves v if it has changed and the interval has passed
+func (a *autosaver) update(v *vmf.Vmf) {
+	if !a.dirty || a.interval <= 0 || time.Since(a.lastSave) < a.interval {
+		return
+	}
+
+	if err := a.save(v); err != nil {
+		fmt.Println("Autosave failed:", err)
+	}
+}
+
+// save writes v over the oldest backup
+func (a *autosaver) save(v *vmf.Vmf) error {
+	slot := 0
+	var oldest time.Time
+
+	for i := 0; i < a.backups; i++ {
+		info, err := os.Stat(a.backupPath(i))
+		if err != nil {
+			// Unused slot
+			slot = i
+			break
+		}
+
+		if i == 0 || info.ModTime().Before(oldest) {
+			slot, oldest = i, info.ModTime()
+		}
+	}
+
+	a.lastSave = time.Now()
+
+	if err := vmf.Save(a.backupPath(slot), v); err != nil {
+		return err
+	}
+
+	a.dirty = false
+
+	return nil
+}
+
+// emergencySave saves v as quickly as possible before forgery exits
+func (a *autosaver) emergencySave(v *vmf.Vmf) error {
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return err
+	}
+
+	return vmf.Save(a.emergencyPath(), v)
+}
+
+func (f *Forgery) startAutosave() {
+	f.autosave = newAutosaver(f.AutosaveDir, f.AutosaveInterval, f.AutosaveBackups, f.MapPath)
+
+	if f.autosave.uncleanShutdown() {
+		f.recoveryPath, f.recoveryTime, f.showRecovery = f.autosave.newestBackup()
+	}
+
+	if err := f.autosave.start(); err != nil {
+		fmt.Println("Unable to start autosaving:", err)
+	}
+
+	events.Subscribe(world.WorldChanged, func(_ string, _ interface{}) {
+		f.autosave.dirty = true
+	})
+}
+
+func (f *Forgery) emergencySave() {
+	if f.autosave == nil || f.vmf == nil {
+		return
+	}
+
+	if err := f.autosave.emergencySave(f.vmf); err != nil {
+		fmt.Println("Emergency save failed:", err)
+		return
+	}
+
+	fmt.Println("Saved emergency backup to", f.autosave.emergencyPath())
+}
+
+// recoveryWindow asks whether to recover the map from a backup
+// after forgery did not exit cleanly
+func (f *Forgery) recoveryWindow() {
+	imgui.BeginV("Recover map", nil, imgui.WindowFlagsAlwaysAutoResize)
+
+	imgui.Text("Forgery did not shut down cleanly last time.")
+	imgui.Text(fmt.Sprintf("Recover from %s (saved %s)?", f.recoveryPath, f.recoveryTime.Format(time.RFC1123)))
+
+	if imgui.Button("Recover") {
+		f.recoverMap()
+		f.showRecovery = false
+		f.openSceneWhenReady()
+	}
+
+	imgui.SameLine()
+
+	if imgui.Button("Discard") {
+		f.showRecovery = false
+		f.openSceneWhenReady()
+	}
+
+	imgui.End()
+}
+
+func (f *Forgery) recoverMap() {
+	v, err := vmf.LoadVmf(f.recoveryPath)
+	if err != nil {
+		fmt.Println("Unable to recover", f.recoveryPath+":", err)
+		return
+	}
+
+	f.world.Close()
+
+	f.vmf = v
+	f.world = v.Worldspawn()
+
+	// Make sure the recovered map gets backed up again
+	f.autosave.dirty = true
+}
@@ -0,0 +1,158 @@
+package forgery
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/emily33901/forgery/core/events"
+	"github.com/emily33901/forgery/core/vmf"
+	"github.com/emily33901/forgery/core/world"
+)
+
+func emptyVmf(t *testing.T) *vmf.Vmf {
+	t.Helper()
+
+	events.Init()
+
+	w := world.New(nil)
+	t.Cleanup(w.Close)
+
+	return vmf.NewVmf(vmf.NewVersionInfo(400, 8000, 1, 100, false), &vmf.VisGroups{}, w, vmf.NewCameras(0, nil))
+}
+
+// setModTime makes path look like it was written at when
+func setModTime(t *testing.T, path string, when time.Time) {
+	t.Helper()
+
+	if err := os.Chtimes(path, when, when); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAutosaveRotation(t *testing.T) {
+	a := newAutosaver(t.TempDir(), time.Minute, 3, "maps/test_map.vmf")
+	v := emptyVmf(t)
+
+	start := time.Now().Add(-time.Hour)
+
+	// Empty slots are used in order
+	for slot := 0; slot < 3; slot++ {
+		if err := a.save(v); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(a.backupPath(slot)); err != nil {
+			t.Fatalf("save %d did not write slot %d: %v", slot, slot, err)
+		}
+
+		setModTime(t, a.backupPath(slot), start.Add(time.Duration(slot)*time.Minute))
+	}
+
+	// Then the oldest is written over
+	setModTime(t, a.backupPath(0), start.Add(10*time.Minute))
+
+	if err := a.save(v); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, _ := os.Stat(a.backupPath(1)); !info.ModTime().After(start.Add(10 * time.Minute)) {
+		t.Error("the oldest backup was not written over")
+	}
+
+	if path, _, ok := a.newestBackup(); !ok || path != a.backupPath(1) {
+		t.Errorf("newest backup is %s, want %s", path, a.backupPath(1))
+	}
+
+	// Nothing past the number of backups is ever written
+	if _, err := os.Stat(a.backupPath(3)); err == nil {
+		t.Error("a 4th backup was written")
+	}
+}
+
+func TestNewestBackup(t *testing.T) {
+	a := newAutosaver(t.TempDir(), time.Minute, 2, "")
+	v := emptyVmf(t)
+
+	if _, _, ok := a.newestBackup(); ok {
+		t.Fatal("found a backup before anything was saved")
+	}
+
+	if err := a.save(v); err != nil {
+		t.Fatal(err)
+	}
+
+	if path, _, ok := a.newestBackup(); !ok || path != a.backupPath(0) {
+		t.Errorf("newest backup is %s, want %s", path, a.backupPath(0))
+	}
+
+	if err := a.emergencySave(v); err != nil {
+		t.Fatal(err)
+	}
+
+	setModTime(t, a.backupPath(0), time.Now().Add(-time.Hour))
+
+	if path, _, ok := a.newestBackup(); !ok || path != a.emergencyPath() {
+		t.Errorf("newest backup is %s, want %s", path, a.emergencyPath())
+	}
+
+	// Backups of other maps are not ours
+	other := newAutosaver(a.dir, time.Minute, 2, "other.vmf")
+	if path, _, ok := other.newestBackup(); ok {
+		t.Errorf("another map found %s", path)
+	}
+}
+
+func TestUncleanShutdown(t *testing.T) {
+	dir := t.TempDir()
+	a := newAutosaver(dir, time.Minute, 1, "test_map.vmf")
+
+	if a.uncleanShutdown() {
+		t.Fatal("unclean before anything ran")
+	}
+
+	if err := a.start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// This process is still running the map
+	if a.uncleanShutdown() {
+		t.Error("a running forgery counted as an unclean shutdown")
+	}
+
+	// Other maps have their own marker
+	if other := newAutosaver(dir, time.Minute, 1, "other.vmf"); other.uncleanShutdown() {
+		t.Error("another map saw the marker")
+	}
+
+	a.stop()
+
+	if a.uncleanShutdown() {
+		t.Error("unclean after stopping")
+	}
+
+	// A process that has exited left its marker behind
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(a.markerPath(), []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.uncleanShutdown() {
+		t.Error("a marker from an exited process was not an unclean shutdown")
+	}
+
+	if err := ioutil.WriteFile(a.markerPath(), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.uncleanShutdown() {
+		t.Error("an unreadable marker was not an unclean shutdown")
+	}
+}
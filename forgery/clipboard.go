@@ -0,0 +1,91 @@
+package forgery
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/emily33901/forgery/core/vmf"
+	"github.com/emily33901/forgery/core/world"
+	"github.com/inkyblackness/imgui-go"
+)
+
+// copySelection puts the selected solids and entities onto the clipboard as
+// vmf text so that they can be pasted into this or another map (or hammer)
+func (f *Forgery) copySelection() {
+	selection := f.world.Selection()
+
+	solids := []world.Solid{}
+	for _, id := range selection.Solids() {
+		if s := f.world.Solid(id); s != nil {
+			solids = append(solids, *s)
+		}
+	}
+
+	entities := []world.Entity{}
+	for _, id := range selection.Entities() {
+		if e := f.world.Entity(id); e != nil {
+			entities = append(entities, *e)
+		}
+	}
+
+	if len(solids) == 0 && len(entities) == 0 {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := vmf.WriteClipboard(buf, solids, entities); err != nil {
+		fmt.Println("Unable to copy:", err)
+		return
+	}
+
+	f.imguiPlatform.SetClipboardText(buf.String())
+}
+
+// paste pastes the solids and entities on the clipboard into the world
+func (f *Forgery) paste(opts world.PasteOptions) {
+	text, err := f.imguiPlatform.ClipboardText()
+	if err != nil {
+		fmt.Println("Unable to paste:", err)
+		return
+	}
+
+	solids, entities, err := vmf.ReadClipboard(strings.NewReader(text))
+	if err != nil {
+		fmt.Println("Unable to paste:", err)
+		return
+	}
+
+	f.world.Paste(solids, entities, opts)
+}
+
+func (f *Forgery) pasteSpecialWindow() {
+	imgui.BeginV("Paste special", &f.showPasteSpecial, imgui.WindowFlagsAlwaysAutoResize)
+
+	opts := &f.pasteOptions
+
+	copies := int32(opts.Copies)
+	if imgui.DragInt("Copies", &copies) {
+		if copies < 1 {
+			copies = 1
+		}
+		opts.Copies = int(copies)
+	}
+
+	imgui.Text("Offset")
+	imgui.DragFloat("X##offset", &opts.Offset.X)
+	imgui.DragFloat("Y##offset", &opts.Offset.Y)
+	imgui.DragFloat("Z##offset", &opts.Offset.Z)
+
+	imgui.Text("Rotation")
+	imgui.DragFloat("X##rotation", &opts.Rotation.X)
+	imgui.DragFloat("Y##rotation", &opts.Rotation.Y)
+	imgui.DragFloat("Z##rotation", &opts.Rotation.Z)
+
+	if imgui.Button("Paste") {
+		f.paste(*opts)
+		f.showPasteSpecial = false
+	}
+
+	imgui.End()
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emily33901/forgery/core/filesystem"
+	"github.com/emily33901/forgery/forgery/loader/keyvalues"
+	"github.com/emily33901/forgery/forgery/steam"
+)
+
+// DefaultMaterial is put on new faces when a game does not say otherwise
+const DefaultMaterial = "dev/dev_measuregeneric01b"
+
+// Game is everything needed to edit and compile maps for one game
+type Game struct {
+	Name string `json:"name"`
+
+	// Directory that gameinfo.txt is in, such as
+	// .../Counter-Strike Global Offensive/csgo
+	GameDir string `json:"gameDir"`
+	// Path to gameinfo.txt if it is not in GameDir
+	GameInfo string `json:"gameInfo,omitempty"`
+
+	FGDs            []string `json:"fgds"`
+	DefaultMaterial string   `json:"defaultMaterial"`
+
+	Tools Tools `json:"tools"`
+
+	// Where vmfs are kept and where compiled maps go
+	MapDir string `json:"mapDir"`
+	BSPDir string `json:"bspDir"`
+}
+
+// Tools are the programs used to compile and run maps
+type Tools struct {
+	BSP     string `json:"bsp"`
+	Vis     string `json:"vis"`
+	Light   string `json:"light"`
+	GameExe string `json:"gameExe"`
+}
+
+// GameInfoPath returns where the gameinfo.txt of this game is
+func (g *Game) GameInfoPath() string {
+	if g.GameInfo != "" {
+		return g.GameInfo
+	}
+
+	return filepath.Join(g.GameDir, "gameinfo.txt")
+}
+
+// Material returns the material to put on new faces
+func (g *Game) Material() string {
+	if g.DefaultMaterial != "" {
+		return g.DefaultMaterial
+	}
+
+	return DefaultMaterial
+}
+
+// Filesystem reads the gameinfo.txt of this game and builds a filesystem with
+// everything it mounts. Games from other Steam apps are found in the Steam
+// install. If anything could not be mounted filesystem.MountErrors
+// are returned along with the filesystem.
+func (g *Game) Filesystem() (*filesystem.Filesystem, error) {
+	gameInfo, err := keyvalues.FromDisk(g.GameInfoPath())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &filesystem.GameInfoOptions{
+		ResolveAppId: func(appId int) (string, error) {
+			root, err := steam.FindRoot()
+			if err != nil {
+				return "", err
+			}
+
+			return steam.AppInstallDir(root, appId)
+		},
+	}
+
+	return filesystem.CreateFromGameDir(g.GameDir, gameInfo, opts)
+}
+
+// Config is every game that forgery knows about
+type Config struct {
+	Games []Game `json:"games"`
+
+	// Name of the game to use when none is asked for
+	DefaultGame string `json:"defaultGame,omitempty"`
+}
+
+// DefaultPath returns where the config is kept if nothing else is asked for
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+
+	return filepath.Join(dir, "forgery", "games.json")
+}
+
+// Load reads the config at path. If there is nothing
+// there yet an empty config is returned.
+func Load(path string) (*Config, error) {
+	c := &Config{Games: []Game{}}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Save writes the config to path
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write somewhere else first so that a failed save
+	// does not lose the config that is already there
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Game returns the game called name (ignoring case) or nil
+func (c *Config) Game(name string) *Game {
+	for i := range c.Games {
+		if strings.EqualFold(c.Games[i].Name, name) {
+			return &c.Games[i]
+		}
+	}
+
+	return nil
+}
+
+// AddGame adds g, replacing any game with the same name
+func (c *Config) AddGame(g Game) {
+	if existing := c.Game(g.Name); existing != nil {
+		*existing = g
+		return
+	}
+
+	c.Games = append(c.Games, g)
+}
+
+// Selected returns the game called name, or the default game if name
+// is empty. If there is only one game that is used. nil is returned
+// when the user has to pick.
+func (c *Config) Selected(name string) *Game {
+	if name != "" {
+		return c.Game(name)
+	}
+
+	if c.DefaultGame != "" {
+		return c.Game(c.DefaultGame)
+	}
+
+	if len(c.Games) == 1 {
+		return &c.Games[0]
+	}
+
+	return nil
+}
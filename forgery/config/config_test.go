@@ -0,0 +1,102 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadHammer(t *testing.T) {
+	games, err := ReadHammer(filepath.Join("testdata", "GameConfig.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Game names and paths have spaces in them
+	hl2 := `C:\Steam\steamapps\common\Half-Life 2`
+
+	want := []Game{
+		{
+			Name:    "Half-Life 2",
+			GameDir: hl2 + `\hl2`,
+			FGDs:    []string{hl2 + `\bin\halflife2.fgd`, hl2 + `\bin\base.fgd`},
+			Tools: Tools{
+				BSP:     hl2 + `\bin\vbsp.exe`,
+				Vis:     hl2 + `\bin\vvis.exe`,
+				Light:   hl2 + `\bin\vrad.exe`,
+				GameExe: hl2 + `\hl2.exe`,
+			},
+			MapDir: hl2 + `\sdk_content\mapsrc`,
+			BSPDir: hl2 + `\hl2\maps`,
+		},
+		// Broken has no GameDir so is skipped
+		{
+			Name:    "Counter-Strike: Source",
+			GameDir: `C:\Steam\steamapps\common\Counter-Strike Source\cstrike`,
+			FGDs:    []string{`C:\Steam\steamapps\common\Counter-Strike Source\bin\cstrike.fgd`},
+		},
+	}
+
+	if !reflect.DeepEqual(games, want) {
+		t.Errorf("read %+v\nwant %+v", games, want)
+	}
+
+	c := &Config{}
+	if _, err := c.ImportHammer(filepath.Join("testdata", "GameConfig.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Game("half-life 2") == nil || c.Game("Broken") != nil || len(c.Games) != 2 {
+		t.Errorf("imported %+v", c.Games)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "games.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Games == nil || len(c.Games) != 0 || c.DefaultGame != "" {
+		t.Errorf("missing config loaded as %+v", c)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	c := &Config{DefaultGame: "Half-Life 2"}
+
+	c.AddGame(Game{
+		Name:            "Half-Life 2",
+		GameDir:         "/games/hl2",
+		FGDs:            []string{"/games/bin/halflife2.fgd"},
+		DefaultMaterial: "dev/dev_measurewall01a",
+		Tools:           Tools{BSP: "vbsp", Vis: "vvis", Light: "vrad", GameExe: "hl2"},
+		MapDir:          "/maps/src",
+		BSPDir:          "/games/hl2/maps",
+	})
+
+	c.AddGame(Game{Name: "Portal", GameDir: "/games/portal", GameInfo: "/elsewhere/gameinfo.txt", FGDs: []string{}})
+
+	// Replaces rather than adds
+	c.AddGame(Game{Name: "portal", GameDir: "/games/portal", FGDs: []string{}})
+
+	// Saving makes the directory it goes in
+	path := filepath.Join(t.TempDir(), "forgery", "games.json")
+
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded, c) {
+		t.Errorf("loaded %+v\nwant %+v", loaded, c)
+	}
+
+	if g := loaded.Selected(""); g == nil || g.Name != "Half-Life 2" {
+		t.Errorf("selected %+v, want the default game", g)
+	}
+}
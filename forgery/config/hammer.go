@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/emily33901/forgery/forgery/loader/keyvalues"
+)
+
+// ImportHammer adds the games in the Hammer GameConfig.txt at path,
+// replacing any with the same name, and returns them
+func (c *Config) ImportHammer(path string) ([]Game, error) {
+	games, err := ReadHammer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range games {
+		c.AddGame(g)
+	}
+
+	return games, nil
+}
+
+// quotedSpace stands in for the spaces in block names while reading.
+// The keyvalues reader splits keys on spaces and game names are full of them.
+const quotedSpace = "\x1f"
+
+// keepBlockNameSpaces swaps the spaces in block names for quotedSpace
+func keepBlockNameSpaces(text string) string {
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		// A block name is a quoted string on its own
+		name := strings.TrimSpace(line)
+		if len(name) > 2 && strings.Count(name, `"`) == 2 && name[0] == '"' && name[len(name)-1] == '"' {
+			lines[i] = strings.Replace(line, name, strings.ReplaceAll(name, " ", quotedSpace), 1)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ReadHammer reads the games from a Hammer GameConfig.txt
+func ReadHammer(path string) ([]Game, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := keyvalues.FromReader(strings.NewReader(keepBlockNameSpaces(string(data))))
+	if err != nil {
+		return nil, err
+	}
+
+	gamesNode, err := root.Find("Games")
+	if err != nil {
+		return nil, errors.New("no games in " + path)
+	}
+
+	children, err := gamesNode.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	games := []Game{}
+
+	for _, node := range children {
+		g := Game{
+			Name: strings.ReplaceAll(node.Key(), quotedSpace, " "),
+			FGDs: []string{},
+		}
+
+		if dir, err := node.Find("GameDir"); err == nil {
+			g.GameDir, _ = dir.AsString()
+		}
+
+		if hammer, err := node.Find("Hammer"); err == nil {
+			settings, _ := hammer.Children()
+
+			for _, kv := range settings {
+				value, _ := kv.AsString()
+				key := strings.ToLower(kv.Key())
+
+				switch {
+				// GameData0, GameData1 and so on
+				case strings.HasPrefix(key, "gamedata"):
+					if value != "" {
+						g.FGDs = append(g.FGDs, value)
+					}
+				case key == "bsp":
+					g.Tools.BSP = value
+				case key == "vis":
+					g.Tools.Vis = value
+				case key == "light":
+					g.Tools.Light = value
+				case key == "gameexe":
+					g.Tools.GameExe = value
+				case key == "mapdir":
+					g.MapDir = value
+				case key == "bspdir":
+					g.BSPDir = value
+				}
+			}
+		}
+
+		if g.GameDir == "" {
+			// Not something that can be edited
+			continue
+		}
+
+		games = append(games, g)
+	}
+
+	return games, nil
+}
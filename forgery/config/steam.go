@@ -0,0 +1,50 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/emily33901/forgery/forgery/steam"
+)
+
+// ImportSteam adds every Source game installed with the Steam at root,
+// replacing any with the same name, and returns them.
+// If root is empty the usual places are searched for Steam.
+func (c *Config) ImportSteam(root string) ([]Game, error) {
+	if root == "" {
+		var err error
+		if root, err = steam.FindRoot(); err != nil {
+			return nil, err
+		}
+	}
+
+	steamGames, err := steam.SourceGames(root)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []Game{}
+
+	for _, sg := range steamGames {
+		for _, gameInfo := range sg.GameInfos {
+			dir := filepath.Dir(gameInfo)
+
+			g := Game{
+				Name:    sg.Name,
+				GameDir: dir,
+				FGDs:    []string{},
+				MapDir:  filepath.Join(dir, "maps"),
+				BSPDir:  filepath.Join(dir, "maps"),
+			}
+
+			// Half-Life 2 and others have a game in each directory
+			if len(sg.GameInfos) > 1 {
+				g.Name += " (" + filepath.Base(dir) + ")"
+			}
+
+			c.AddGame(g)
+			games = append(games, g)
+		}
+	}
+
+	return games, nil
+}
@@ -2,14 +2,14 @@ package forgery
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/emily33901/forgery/core/events"
 	"github.com/emily33901/forgery/core/filesystem"
 	"github.com/emily33901/forgery/core/vmf"
 	"github.com/emily33901/forgery/core/world"
+	"github.com/emily33901/forgery/forgery/config"
 	imguiBackend "github.com/emily33901/forgery/forgery/imgui"
-	"github.com/emily33901/forgery/forgery/loader/keyvalues"
 	"github.com/emily33901/forgery/forgery/render"
 	"github.com/emily33901/forgery/forgery/render/adapters"
 	"github.com/emily33901/forgery/forgery/windows"
@@ -31,12 +31,54 @@ type Forgery struct {
 
 	ShouldQuit bool
 
-	showDemoWindow  bool
-	showAboutWindow bool
+	// Game settings, these can be changed before Run
+	Config     *config.Config
+	ConfigPath string
+	GameName   string
+
+	// Map to open and compiled maps whose packed content is
+	// mounted, these can be changed before Run
+	MapPath string
+	Bsps    []string
+
+	// Autosave settings, these can be changed before Run
+	AutosaveDir      string
+	AutosaveInterval time.Duration
+	AutosaveBackups  int
+
+	// How often loose materials and textures are checked
+	// for changes, 0 to disable. This can be changed before Run
+	HotReloadInterval time.Duration
+
+	watcher *filesystem.Watcher
+
+	autosave     *autosaver
+	showRecovery bool
+	recoveryPath string
+	recoveryTime time.Time
+
+	showDemoWindow   bool
+	showAboutWindow  bool
+	showPasteSpecial bool
+	showNodraw       bool
+
+	showReplaceMaterials bool
+	replaceOptions       world.ReplaceOptions
+	replaceResult        string
+
+	pasteOptions world.PasteOptions
+	hiddenFaces  []world.HiddenFace
 
 	Adapter render.Adapter
 
+	game           *config.Game
+	showGameSelect bool
+	gameSelection  int
+	hammerConfig   string
+	gameError      string
+
 	fs    *filesystem.Filesystem
+	vmf   *vmf.Vmf
 	world *world.World
 }
 
@@ -54,6 +96,15 @@ func Get() *Forgery {
 		IDispatcher: core.NewDispatcher(),
 	}
 	f.showDemoWindow = true
+	f.pasteOptions.Copies = 1
+
+	f.Config = &config.Config{}
+	f.ConfigPath = config.DefaultPath()
+
+	f.AutosaveDir = DefaultAutosaveDir()
+	f.AutosaveInterval = DefaultAutosaveInterval
+	f.AutosaveBackups = DefaultAutosaveBackups
+	f.HotReloadInterval = DefaultHotReloadInterval
 
 	events.Set(f.IDispatcher)
 
@@ -78,33 +129,7 @@ func Get() *Forgery {
 	f.renderer = renderer.NewRenderer(f.window.Gls())
 	err = f.renderer.AddDefaultShaders()
 
-	gameinfo, err := keyvalues.FromDisk("E:\\Steam\\steamapps\\common\\Counter-Strike Global Offensive\\csgo\\" + "gameinfo.txt")
-
-	if err != nil {
-		panic(err)
-	}
-
-	f.fs = filesystem.CreateFromGameDir("E:\\Steam\\steamapps\\common\\Counter-Strike Global Offensive\\csgo\\", gameinfo)
-
-	fmt.Println("Found", len(f.fs.AllPaths()), "Paths")
-
-	if err != nil {
-		panic(err)
-	}
-
-	for _, f := range f.fs.AllPaths() {
-		if strings.HasSuffix(f, ".vtf") && strings.Contains(f, "error") {
-			fmt.Println(f)
-		}
-	}
-
-	vmf, err := vmf.LoadVmf("assets\\default_cs_small.vmf")
-
-	if err != nil {
-		panic(err)
-	}
-
-	f.world = vmf.Worldspawn()
+	f.MapPath = DefaultMapPath
 
 	return f
 }
@@ -128,6 +153,26 @@ func (f *Forgery) buildUI() {
 		f.aboutWindow()
 	}
 
+	if f.showRecovery {
+		f.recoveryWindow()
+	}
+
+	if f.showGameSelect {
+		f.gameSelectWindow()
+	}
+
+	if f.showPasteSpecial {
+		f.pasteSpecialWindow()
+	}
+
+	if f.showNodraw {
+		f.nodrawWindow()
+	}
+
+	if f.showReplaceMaterials {
+		f.replaceMaterialsWindow()
+	}
+
 	// Global forgery menu
 	if imgui.BeginMainMenuBar() {
 		f.menuBar()
@@ -149,10 +194,60 @@ func (f *Forgery) aboutWindow() {
 }
 
 func (f *Forgery) menuBar() {
-	if imgui.MenuItem("New scene") {
+	if imgui.MenuItemV("New scene", "", false, f.fs != nil) {
 		f.newSceneWindow()
 	}
 
+	if imgui.BeginMenu("Edit") {
+		undoName, canUndo := f.world.History().CanUndo()
+		if imgui.MenuItemV("Undo "+undoName, "", false, canUndo) {
+			f.world.Undo()
+		}
+
+		redoName, canRedo := f.world.History().CanRedo()
+		if imgui.MenuItemV("Redo "+redoName, "", false, canRedo) {
+			f.world.Redo()
+		}
+
+		imgui.Separator()
+
+		hasSelection := !f.world.Selection().Empty()
+		if imgui.MenuItemV("Copy", "", false, hasSelection) {
+			f.copySelection()
+		}
+
+		if imgui.MenuItem("Paste") {
+			f.paste(world.PasteOptions{})
+		}
+
+		if imgui.MenuItem("Paste special") {
+			f.showPasteSpecial = true
+		}
+
+		imgui.EndMenu()
+	}
+
+	if imgui.BeginMenu("Tools") {
+		if imgui.MenuItem("Replace materials") {
+			f.showReplaceMaterials = true
+		}
+
+		if imgui.MenuItem("Nodraw hidden faces") {
+			f.findHiddenFaces()
+		}
+
+		canMerge := len(f.world.Selection().Solids()) > 1
+		if imgui.MenuItemV("Merge solids", "", false, canMerge) {
+			f.mergeSelection(false)
+		}
+
+		if imgui.MenuItemV("Merge solids into hull", "", false, canMerge) {
+			f.mergeSelection(true)
+		}
+
+		imgui.EndMenu()
+	}
+
 	if imgui.BeginMenu("Other") {
 		if imgui.MenuItem("About") {
 			f.showAboutWindow = true
@@ -166,6 +261,12 @@ func (f *Forgery) menuBar() {
 	}
 }
 
+func (f *Forgery) mergeSelection(hull bool) {
+	if _, err := f.world.MergeSolids(f.world.Selection().Solids(), hull); err != nil {
+		fmt.Println("Unable to merge:", err)
+	}
+}
+
 func (f *Forgery) render() {
 	windows.Iter(func(_ string, v *windows.SceneWindow) {
 		v.Render(f.renderer)
@@ -175,8 +276,36 @@ func (f *Forgery) render() {
 func (f *Forgery) Run() {
 	clearColor := [3]float32{0.1, 0.1, 0.1}
 
+	var err error
+	f.vmf, err = vmf.LoadVmf(f.MapPath)
+
+	if err != nil {
+		panic(err)
+	}
+
+	f.world = f.vmf.Worldspawn()
+
+	f.startAutosave()
+
+	// Try not to lose any work if something goes wrong
+	defer func() {
+		if r := recover(); r != nil {
+			f.emergencySave()
+			panic(r)
+		}
+	}()
+
 	i := 0
-	f.newSceneWindow()
+
+	if game := f.Config.Selected(f.GameName); game != nil {
+		if err := f.loadGame(game); err != nil {
+			f.gameError = err.Error()
+		}
+	}
+
+	f.showGameSelect = f.fs == nil
+
+	f.openSceneWhenReady()
 
 	for !f.ShouldQuit && !f.window.(*window.GlfwWindow).ShouldClose() {
 		i++
@@ -196,7 +325,12 @@ func (f *Forgery) Run() {
 		f.imguiRenderer.PreRender(clearColor)
 		f.imguiRenderer.Render(f.imguiPlatform.DisplaySize(), f.imguiPlatform.FramebufferSize(), imgui.RenderedDrawData())
 		f.imguiPlatform.PostRender()
+
+		f.autosave.update(f.vmf)
+		f.hotReload()
 	}
 
+	f.stopHotReload()
+	f.autosave.stop()
 	f.window.Destroy()
 }
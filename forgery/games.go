@@ -0,0 +1,142 @@
+package forgery
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/emily33901/forgery/core/filesystem"
+	"github.com/emily33901/forgery/forgery/config"
+	"github.com/inkyblackness/imgui-go"
+)
+
+// DefaultMapPath is the map that is opened if no other is asked for
+var DefaultMapPath = filepath.Join("assets", "default_cs_small.vmf")
+
+// loadGame builds the filesystem for game and mounts the packed content of Bsps
+func (f *Forgery) loadGame(game *config.Game) error {
+	fs, err := game.Filesystem()
+
+	mountErrs := filesystem.MountErrors{}
+	if errors.As(err, &mountErrs) {
+		// Whatever could be mounted is still usable
+		for _, mountErr := range mountErrs {
+			fmt.Println(mountErr)
+		}
+	} else if err != nil {
+		return fmt.Errorf("unable to load %s: %v", game.Name, err)
+	}
+
+	for _, path := range f.Bsps {
+		if err := fs.RegisterBsp(path, 0); err != nil {
+			fmt.Println("Unable to mount", path+":", err)
+		}
+	}
+
+	fmt.Println("Found", len(fs.AllPaths()), "Paths in", game.Name)
+
+	f.game = game
+	f.fs = fs
+
+	f.startHotReload()
+
+	return nil
+}
+
+// openSceneWhenReady opens the first scene once a game has been
+// picked and the map is not waiting to be recovered
+func (f *Forgery) openSceneWhenReady() {
+	if f.fs == nil || f.showGameSelect || f.showRecovery {
+		return
+	}
+
+	f.newSceneWindow()
+}
+
+// importHammerConfig adds the games in the Hammer GameConfig.txt at path
+// to the config and saves it
+func (f *Forgery) importHammerConfig(path string) error {
+	if _, err := f.Config.ImportHammer(path); err != nil {
+		return err
+	}
+
+	return f.Config.Save(f.ConfigPath)
+}
+
+// importSteamGames adds the Source games installed with Steam
+// to the config and saves it
+func (f *Forgery) importSteamGames() error {
+	if _, err := f.Config.ImportSteam(""); err != nil {
+		return err
+	}
+
+	return f.Config.Save(f.ConfigPath)
+}
+
+// gameSelectWindow asks which game to edit the map for
+func (f *Forgery) gameSelectWindow() {
+	imgui.BeginV("Select game", nil, imgui.WindowFlagsAlwaysAutoResize)
+
+	if len(f.Config.Games) == 0 {
+		imgui.Text("No games are set up yet. Find the ones installed with Steam,")
+		imgui.Text("import them from Hammer's GameConfig.txt")
+		imgui.Text("or add them to " + f.ConfigPath)
+	}
+
+	for i, g := range f.Config.Games {
+		if imgui.RadioButton(g.Name, f.gameSelection == i) {
+			f.gameSelection = i
+		}
+	}
+
+	if f.gameSelection < len(f.Config.Games) {
+		if imgui.Button("Open") {
+			game := &f.Config.Games[f.gameSelection]
+
+			if err := f.loadGame(game); err != nil {
+				f.gameError = err.Error()
+			} else {
+				f.gameError = ""
+				f.showGameSelect = false
+				f.openSceneWhenReady()
+			}
+		}
+
+		imgui.SameLine()
+
+		if imgui.Button("Open by default") {
+			f.Config.DefaultGame = f.Config.Games[f.gameSelection].Name
+
+			if err := f.Config.Save(f.ConfigPath); err != nil {
+				f.gameError = err.Error()
+			}
+		}
+	}
+
+	imgui.Separator()
+
+	imgui.InputText("GameConfig.txt", &f.hammerConfig)
+	imgui.SameLine()
+
+	if imgui.Button("Import") {
+		if err := f.importHammerConfig(f.hammerConfig); err != nil {
+			f.gameError = err.Error()
+		} else {
+			f.gameError = ""
+		}
+	}
+
+	if imgui.Button("Find Steam games") {
+		if err := f.importSteamGames(); err != nil {
+			f.gameError = err.Error()
+		} else {
+			f.gameError = ""
+		}
+	}
+
+	if f.gameError != "" {
+		imgui.Text(f.gameError)
+	}
+
+	imgui.End()
+}
@@ -0,0 +1,51 @@
+package forgery
+
+import (
+	"time"
+
+	"github.com/emily33901/forgery/core/filesystem"
+	"github.com/emily33901/forgery/core/materials"
+)
+
+// DefaultHotReloadInterval is how often the materials
+// and textures in use are checked for changes
+const DefaultHotReloadInterval = time.Second
+
+// startHotReload watches the materials and textures read from the current
+// game from now on so that they are reloaded when they are changed
+func (f *Forgery) startHotReload() {
+	f.stopHotReload()
+
+	if f.HotReloadInterval <= 0 {
+		return
+	}
+
+	f.watcher = f.fs.Watch(f.HotReloadInterval, filesystem.ExtensionVmt, filesystem.ExtensionVtf)
+}
+
+func (f *Forgery) stopHotReload() {
+	if f.watcher != nil {
+		f.watcher.Stop()
+		f.watcher = nil
+	}
+}
+
+// hotReload reloads whatever has changed since it was last called.
+// It has to be called on the render thread as textures are uploaded again.
+func (f *Forgery) hotReload() {
+	if f.watcher == nil {
+		return
+	}
+
+	changed := f.watcher.Changed()
+	if len(changed) == 0 {
+		return
+	}
+
+	// New files have to be found too
+	f.fs.Refresh()
+
+	for _, file := range changed {
+		materials.Reload(file, f.fs)
+	}
+}
@@ -1,6 +1,7 @@
 package keyvalues
 
 import (
+	"io"
 	"os"
 
 	keyvalues "github.com/galaco/KeyValues"
@@ -11,6 +12,12 @@ func FromDisk(path string) (*keyvalues.KeyValue, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer stream.Close()
+
+	return FromReader(stream)
+}
+
+func FromReader(stream io.Reader) (*keyvalues.KeyValue, error) {
 	kvReader := keyvalues.NewReader(stream)
 
 	kv, err := kvReader.Read()
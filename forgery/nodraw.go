@@ -0,0 +1,66 @@
+package forgery
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emily33901/forgery/core/world"
+	"github.com/inkyblackness/imgui-go"
+)
+
+// findHiddenFaces finds the hidden faces of the selected solids (or the whole
+// map if nothing is selected) and selects them so that they can be previewed
+func (f *Forgery) findHiddenFaces() {
+	f.hiddenFaces = f.world.FindHiddenFaces(f.world.Selection().Solids())
+
+	ids := make([]int, len(f.hiddenFaces))
+	for i, face := range f.hiddenFaces {
+		ids[i] = face.SideId
+	}
+
+	f.world.Selection().SelectSides(world.SelectReplace, ids...)
+	f.showNodraw = true
+}
+
+func (f *Forgery) nodrawWindow() {
+	imgui.BeginV("Nodraw hidden faces", &f.showNodraw, imgui.WindowFlagsAlwaysAutoResize)
+
+	// How many faces of each material would change
+	counts := map[string]int{}
+	area := 0.0
+
+	for _, face := range f.hiddenFaces {
+		counts[face.Material]++
+		area += face.Area
+	}
+
+	materials := make([]string, 0, len(counts))
+	for m := range counts {
+		materials = append(materials, m)
+	}
+	sort.Strings(materials)
+
+	imgui.Text(fmt.Sprintf("%d hidden faces (%.0f units squared)", len(f.hiddenFaces), area))
+	imgui.Separator()
+
+	for _, m := range materials {
+		imgui.Text(fmt.Sprintf("%5d %s", counts[m], m))
+	}
+
+	imgui.Separator()
+
+	if imgui.Button("Apply") {
+		f.world.ApplyNodraw(f.hiddenFaces)
+		f.hiddenFaces = nil
+		f.showNodraw = false
+	}
+
+	imgui.SameLine()
+
+	if imgui.Button("Cancel") {
+		f.hiddenFaces = nil
+		f.showNodraw = false
+	}
+
+	imgui.End()
+}
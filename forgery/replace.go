@@ -0,0 +1,56 @@
+package forgery
+
+import (
+	"fmt"
+
+	"github.com/emily33901/forgery/core/world"
+	"github.com/inkyblackness/imgui-go"
+)
+
+func (f *Forgery) replaceMaterialsWindow() {
+	imgui.BeginV("Replace materials", &f.showReplaceMaterials, imgui.WindowFlagsAlwaysAutoResize)
+
+	opts := &f.replaceOptions
+
+	imgui.InputText("Find", &opts.Find)
+	imgui.InputText("Replace", &opts.Replace)
+
+	modes := []string{"Exact", "Prefix", "Glob", "Regex"}
+	for i, name := range modes {
+		if i != 0 {
+			imgui.SameLine()
+		}
+
+		if imgui.RadioButton(name, opts.Mode == world.MatchMode(i)) {
+			opts.Mode = world.MatchMode(i)
+		}
+	}
+
+	imgui.Checkbox("Selection only", &opts.SelectionOnly)
+
+	visgroup := int32(opts.VisgroupId)
+	if imgui.DragInt("Visgroup (0 for any)", &visgroup) {
+		if visgroup < 0 {
+			visgroup = 0
+		}
+		opts.VisgroupId = int(visgroup)
+	}
+
+	imgui.Checkbox("Rescale textures", &opts.Rescale)
+
+	if imgui.Button("Replace") {
+		changed, err := f.world.ReplaceMaterials(*opts, f.fs)
+
+		if err != nil {
+			f.replaceResult = err.Error()
+		} else {
+			f.replaceResult = fmt.Sprintf("Replaced %d faces", changed)
+		}
+	}
+
+	if f.replaceResult != "" {
+		imgui.Text(f.replaceResult)
+	}
+
+	imgui.End()
+}
@@ -0,0 +1,259 @@
+// Package steam finds Source games installed with Steam
+package steam
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emily33901/forgery/forgery/loader/keyvalues"
+)
+
+// ErrNoSteam is returned when none of the usual places have Steam in them
+var ErrNoSteam = errors.New("unable to find a steam install")
+
+// App is an app installed in a library
+type App struct {
+	AppId int
+	Name  string
+	// Full path of where the app is installed
+	InstallDir string
+}
+
+// Game is an installed app with at least one gameinfo.txt in it
+type Game struct {
+	App
+	// Full path of each gameinfo.txt, such as .../Half-Life 2/hl2/gameinfo.txt
+	GameInfos []string
+}
+
+// Roots returns the places Steam is normally installed on this platform
+func Roots() []string {
+	home, _ := os.UserHomeDir()
+
+	switch runtime.GOOS {
+	case "windows":
+		roots := []string{}
+
+		for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles"} {
+			if dir := os.Getenv(env); dir != "" {
+				roots = append(roots, filepath.Join(dir, "Steam"))
+			}
+		}
+
+		return append(roots, `C:\Program Files (x86)\Steam`, `C:\Program Files\Steam`)
+
+	case "darwin":
+		return []string{filepath.Join(home, "Library", "Application Support", "Steam")}
+	}
+
+	return []string{
+		filepath.Join(home, ".steam", "steam"),
+		filepath.Join(home, ".local", "share", "Steam"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+	}
+}
+
+// FindRoot returns the first of Roots that has Steam in it
+func FindRoot() (string, error) {
+	for _, root := range Roots() {
+		if _, err := os.Stat(filepath.Join(root, "steamapps")); err == nil {
+			return root, nil
+		}
+	}
+
+	return "", ErrNoSteam
+}
+
+// Libraries returns every library of the Steam install at root,
+// starting with root itself
+func Libraries(root string) ([]string, error) {
+	libraries := []string{root}
+
+	kv, err := keyvalues.FromDisk(filepath.Join(root, "steamapps", "libraryfolders.vdf"))
+	if os.IsNotExist(err) {
+		// Only the main library
+		return libraries, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	children, err := kv.Children()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		// Libraries are numbered, the other keys are not libraries
+		if _, err := strconv.Atoi(child.Key()); err != nil {
+			continue
+		}
+
+		// Older versions only have the path, newer ones have a block
+		path, err := child.AsString()
+		if err != nil || path == "" {
+			pathNode, err := child.Find("path")
+			if err != nil {
+				continue
+			}
+
+			path, _ = pathNode.AsString()
+		}
+
+		if path = cleanPath(path); path != "" && !containsPath(libraries, path) {
+			libraries = append(libraries, path)
+		}
+	}
+
+	return libraries, nil
+}
+
+// Apps returns every app installed in library
+func Apps(library string) ([]App, error) {
+	steamapps := filepath.Join(library, "steamapps")
+
+	manifests, err := filepath.Glob(filepath.Join(steamapps, "appmanifest_*.acf"))
+	if err != nil {
+		return nil, err
+	}
+
+	apps := []App{}
+
+	for _, manifest := range manifests {
+		kv, err := keyvalues.FromDisk(manifest)
+		if err != nil {
+			continue
+		}
+
+		app := App{}
+
+		// The reader has already turned the id into a number
+		if node, err := kv.Find("appid"); err == nil {
+			id, _ := node.AsInt()
+			app.AppId = int(id)
+		}
+
+		if node, err := kv.Find("name"); err == nil {
+			app.Name, _ = node.AsString()
+		}
+
+		node, err := kv.Find("installdir")
+		if err != nil {
+			continue
+		}
+
+		installDir, _ := node.AsString()
+		if installDir == "" {
+			continue
+		}
+
+		app.InstallDir = filepath.Join(steamapps, "common", cleanPath(installDir))
+		apps = append(apps, app)
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].AppId < apps[j].AppId
+	})
+
+	return apps, nil
+}
+
+// AppInstallDir returns where appId is installed in any
+// library of the Steam install at root
+func AppInstallDir(root string, appId int) (string, error) {
+	libraries, err := Libraries(root)
+	if err != nil {
+		return "", err
+	}
+
+	for _, library := range libraries {
+		apps, err := Apps(library)
+		if err != nil {
+			continue
+		}
+
+		for _, app := range apps {
+			if app.AppId == appId {
+				return app.InstallDir, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("app %d is not installed", appId)
+}
+
+// SourceGames returns every installed app in any library of the
+// Steam install at root that has a gameinfo.txt
+func SourceGames(root string) ([]Game, error) {
+	libraries, err := Libraries(root)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []Game{}
+
+	for _, library := range libraries {
+		apps, err := Apps(library)
+		if err != nil {
+			continue
+		}
+
+		for _, app := range apps {
+			if gameInfos := findGameInfos(app.InstallDir); len(gameInfos) != 0 {
+				games = append(games, Game{App: app, GameInfos: gameInfos})
+			}
+		}
+	}
+
+	return games, nil
+}
+
+// findGameInfos returns the gameinfo.txt in each directory of installDir.
+// Games such as Half-Life 2 have more than one.
+func findGameInfos(installDir string) []string {
+	dirs, err := ioutil.ReadDir(installDir)
+	if err != nil {
+		return nil
+	}
+
+	gameInfos := []string{}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(installDir, dir.Name(), "gameinfo.txt")
+		if _, err := os.Stat(path); err == nil {
+			gameInfos = append(gameInfos, path)
+		}
+	}
+
+	return gameInfos
+}
+
+// cleanPath undoes the escaping of backslashes in Steam's files
+func cleanPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Clean(strings.ReplaceAll(path, `\\`, `\`))
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if filepath.Clean(p) == path {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,172 @@
+package steam
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, filename string, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeManifest(t *testing.T, library string, appId string, name string, installDir string) {
+	t.Helper()
+
+	writeFile(t, filepath.Join(library, "steamapps", "appmanifest_"+appId+".acf"), `"AppState"
+{
+	"appid"		"`+appId+`"
+	"Universe"		"1"
+	"name"		"`+name+`"
+	"StateFlags"		"4"
+	"installdir"		"`+installDir+`"
+}
+`)
+}
+
+// makeSteam creates a Steam install with one extra library
+// using libraryfolders.vdf, and returns the root and the library
+func makeSteam(t *testing.T, libraryFolders func(root, library string) string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "Steam")
+	library := filepath.Join(dir, "SteamLibrary")
+
+	writeFile(t, filepath.Join(root, "steamapps", "libraryfolders.vdf"), libraryFolders(root, library))
+
+	// Half-Life 2 has a gameinfo.txt for each of its games
+	writeManifest(t, root, "220", "Half-Life 2", "Half-Life 2")
+	writeFile(t, filepath.Join(root, "steamapps", "common", "Half-Life 2", "hl2", "gameinfo.txt"), `"GameInfo" {}`)
+	writeFile(t, filepath.Join(root, "steamapps", "common", "Half-Life 2", "episodic", "gameinfo.txt"), `"GameInfo" {}`)
+
+	// Not a Source game
+	writeManifest(t, root, "228980", "Steamworks Common Redistributables", "Steamworks Shared")
+	writeFile(t, filepath.Join(root, "steamapps", "common", "Steamworks Shared", "_CommonRedist", "readme.txt"), "")
+
+	writeManifest(t, library, "440", "Team Fortress 2", "Team Fortress 2")
+	writeFile(t, filepath.Join(library, "steamapps", "common", "Team Fortress 2", "tf", "gameinfo.txt"), `"GameInfo" {}`)
+
+	return root, library
+}
+
+func oldLibraryFolders(root, library string) string {
+	return `"LibraryFolders"
+{
+	"TimeNextStatsReport"		"1600000000"
+	"ContentStatsID"		"-1234567890"
+	"1"		"` + library + `"
+}
+`
+}
+
+func newLibraryFolders(root, library string) string {
+	return `"libraryfolders"
+{
+	"contentstatsid"		"-1234567890"
+	"0"
+	{
+		"path"		"` + root + `"
+		"label"		""
+		"apps"
+		{
+			"220"		"12345"
+			"228980"		"123"
+		}
+	}
+	"1"
+	{
+		"path"		"` + library + `"
+		"label"		""
+		"apps"
+		{
+			"440"		"12345"
+		}
+	}
+}
+`
+}
+
+func TestSourceGames(t *testing.T) {
+	formats := map[string]func(root, library string) string{
+		"old": oldLibraryFolders,
+		"new": newLibraryFolders,
+	}
+
+	for format, libraryFolders := range formats {
+		t.Run(format, func(t *testing.T) {
+			root, library := makeSteam(t, libraryFolders)
+
+			libraries, err := Libraries(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(libraries, []string{root, library}) {
+				t.Errorf("libraries = %v, want %v", libraries, []string{root, library})
+			}
+
+			games, err := SourceGames(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hl2 := filepath.Join(root, "steamapps", "common", "Half-Life 2")
+			tf2 := filepath.Join(library, "steamapps", "common", "Team Fortress 2")
+
+			want := []Game{
+				{
+					App: App{AppId: 220, Name: "Half-Life 2", InstallDir: hl2},
+					GameInfos: []string{
+						filepath.Join(hl2, "episodic", "gameinfo.txt"),
+						filepath.Join(hl2, "hl2", "gameinfo.txt"),
+					},
+				},
+				{
+					App:       App{AppId: 440, Name: "Team Fortress 2", InstallDir: tf2},
+					GameInfos: []string{filepath.Join(tf2, "tf", "gameinfo.txt")},
+				},
+			}
+
+			if !reflect.DeepEqual(games, want) {
+				t.Errorf("games = %+v, want %+v", games, want)
+			}
+
+			dir, err := AppInstallDir(root, 440)
+			if err != nil || dir != tf2 {
+				t.Errorf("app 440 is in %q (%v), want %q", dir, err, tf2)
+			}
+
+			if _, err := AppInstallDir(root, 10); err == nil {
+				t.Error("expected an error for an app that is not installed")
+			}
+		})
+	}
+}
+
+func TestLibrariesWithoutLibraryFolders(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, "steamapps"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	libraries, err := Libraries(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(libraries, []string{root}) {
+		t.Errorf("libraries = %v, want only %s", libraries, root)
+	}
+}
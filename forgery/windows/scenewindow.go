@@ -5,6 +5,7 @@ import (
 	"math"
 	"sort"
 
+	"github.com/emily33901/forgery/core/events"
 	"github.com/emily33901/forgery/core/filesystem"
 	"github.com/emily33901/forgery/core/manager"
 	"github.com/emily33901/forgery/core/world"
@@ -15,13 +16,22 @@ import (
 	"github.com/g3n/engine/experimental/collision"
 	"github.com/g3n/engine/math32"
 	"github.com/g3n/engine/renderer"
+	"github.com/g3n/engine/window"
 	"github.com/inkyblackness/imgui-go"
 )
 
 const (
+	// Events
+
+	// ObjectSelected tells other systems that an object was clicked on in a scene window
 	ObjectSelected = "Window.ObjectSelected"
 )
 
+type ObjectSelectedEvent struct {
+	SolidId int
+	SideId  int
+}
+
 type SceneWindow struct {
 	core.IDispatcher
 	Scene    *world.World
@@ -43,6 +53,8 @@ type SceneWindow struct {
 	focused       bool
 	dragging      bool
 	lastDragDelta imgui.Vec2
+
+	presentation world.Presentation
 }
 
 var sceneWindows *manager.Manager = manager.NewManager("scenewindow-%d")
@@ -103,10 +115,13 @@ func (w *SceneWindow) Render(r *renderer.Renderer) {
 		w.fb.Resize(int(w.size.X), int(w.size.Y))
 	}
 
-	// Make sure the scene is valid
-	// this could be done on a seperate thread
+	// Upload anything that has finished building
+	// geometry is generated on other threads
 	w.Scene.BuildScene(w.Fs)
 
+	// Scene is shared with other windows
+	w.Scene.SetPresentation(w.presentation)
+
 	w.bind()
 	w.startFrame()
 	err := r.Render(w.Scene.Root, cameras.Get(w.cameraId))
@@ -191,17 +206,24 @@ func (w *SceneWindow) unfocusedControl(deltaTime float32) {
 			r := collision.NewRaycaster(&math32.Vector3{}, &math32.Vector3{})
 			r.SetFromCamera(w.Camera().Camera, normalisedCoords.X, normalisedCoords.Y)
 
-			results := r.IntersectObject(w.Scene.Root, true)
+			// The scene is y up but the world is z up
+			hit, ok := w.Scene.Raycast(world.SwapYZ(r.Origin()), world.SwapYZ(r.Direction()))
 
-			for _, r := range results {
-				fmt.Println("Hit object with id", r.Object.GetNode().LoaderID(), "at distance", r.Distance)
+			// Holding control adds to the selection like hammer
+			ctrl := imgui.IsKeyDown(int(window.KeyLeftControl)) || imgui.IsKeyDown(int(window.KeyRightControl))
 
-				if p := r.Object.GetNode().Parent(); p != nil {
-					fmt.Println("> Parent is ", p.GetNode().LoaderID())
-				}
-
-				// TODO send object-selected event
+			if ok {
+				events.Dispatch(ObjectSelected, &ObjectSelectedEvent{
+					hit.SolidId, hit.SideId,
+				})
 
+				if ctrl {
+					w.Scene.Selection().SelectSolids(world.SelectToggle, hit.SolidId)
+				} else {
+					w.Scene.Selection().SelectSolids(world.SelectReplace, hit.SolidId)
+				}
+			} else if !ctrl {
+				w.Scene.Selection().Clear()
 			}
 			w.lastMouseHitPos = mouseWindowPos
 		}
@@ -283,11 +305,32 @@ func (w *SceneWindow) BuildUI(deltaTime float32) {
 				imgui.EndMenu()
 			}
 
+			if imgui.BeginMenu("View") {
+				if imgui.MenuItemV("Textured", "", w.presentation == world.PresentationTextured, true) {
+					w.presentation = world.PresentationTextured
+				}
+
+				if imgui.MenuItemV("Flat", "", w.presentation == world.PresentationFlat, true) {
+					w.presentation = world.PresentationFlat
+				}
+
+				if imgui.MenuItemV("Wireframe", "", w.presentation == world.PresentationWireframe, true) {
+					w.presentation = world.PresentationWireframe
+				}
+
+				imgui.EndMenu()
+			}
+
 			if imgui.BeginMenu("Debug") {
 				if imgui.MenuItem("Rebuild scene") {
 					w.Scene.MakeDirty()
 				}
 
+				building := w.Scene.Building()
+				if imgui.MenuItemV(fmt.Sprintf("Cancel building (%d solids)", building), "", false, building != 0) {
+					w.Scene.CancelBuilds()
+				}
+
 				imgui.EndMenu()
 			}
 
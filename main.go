@@ -1,10 +1,133 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/emily33901/forgery/core/vmf"
 	"github.com/emily33901/forgery/forgery"
+	"github.com/emily33901/forgery/forgery/config"
 )
 
 func main() {
+	stats := flag.String("stats", "", "print statistics for a vmf and exit")
+	statsJSON := flag.Bool("json", false, "print statistics as json")
+
+	autosaveDir := flag.String("autosave-dir", forgery.DefaultAutosaveDir(), "directory to keep autosaves in")
+	autosaveInterval := flag.Duration("autosave-interval", forgery.DefaultAutosaveInterval, "time between autosaves, 0 to disable")
+	autosaveBackups := flag.Int("autosave-backups", forgery.DefaultAutosaveBackups, "number of autosaves to keep for each map")
+
+	configPath := flag.String("config", config.DefaultPath(), "file that game configurations are kept in")
+	game := flag.String("game", "", "name of the game configuration to use")
+	importHammer := flag.String("import-hammer", "", "add the games in a Hammer GameConfig.txt to the configuration")
+	importSteam := flag.Bool("import-steam", false, "add the Source games installed with Steam to the configuration")
+	steamRoot := flag.String("steam-root", "", "where Steam is installed if it is not in the usual place")
+	mapPath := flag.String("map", forgery.DefaultMapPath, "vmf to open")
+
+	hotReloadInterval := flag.Duration("hot-reload-interval", forgery.DefaultHotReloadInterval, "time between checks for changed materials and textures, 0 to disable")
+
+	bspPath := flag.String("bsp", "", "mount the content packed into a compiled map")
+
+	flag.Parse()
+
+	if *stats != "" {
+		os.Exit(printStats(*stats, *statsJSON))
+	}
+
+	games, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to load", *configPath+":", err)
+		os.Exit(1)
+	}
+
+	if *importHammer != "" {
+		if err := importHammerConfig(games, *importHammer, *configPath); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to import", *importHammer+":", err)
+			os.Exit(1)
+		}
+	}
+
+	if *importSteam {
+		if err := importSteamGames(games, *steamRoot, *configPath); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to find Steam games:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *game != "" && games.Game(*game) == nil {
+		fmt.Fprintln(os.Stderr, "No game configuration called", *game)
+		os.Exit(1)
+	}
+
 	f := forgery.Get()
+	f.AutosaveDir = *autosaveDir
+	f.AutosaveInterval = *autosaveInterval
+	f.AutosaveBackups = *autosaveBackups
+	f.HotReloadInterval = *hotReloadInterval
+
+	f.Config = games
+	f.ConfigPath = *configPath
+	f.GameName = *game
+	f.MapPath = *mapPath
+
+	if *bspPath != "" {
+		f.Bsps = append(f.Bsps, *bspPath)
+	}
+
 	f.Run()
 }
+
+// importHammerConfig adds the games in a Hammer GameConfig.txt
+// to games and saves them to configPath
+func importHammerConfig(games *config.Config, path string, configPath string) error {
+	imported, err := games.ImportHammer(path)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range imported {
+		fmt.Println("Imported", g.Name)
+	}
+
+	return games.Save(configPath)
+}
+
+// importSteamGames adds the Source games installed with
+// the Steam at root to games and saves them to configPath
+func importSteamGames(games *config.Config, root string, configPath string) error {
+	imported, err := games.ImportSteam(root)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range imported {
+		fmt.Println("Found", g.Name, "in", g.GameDir)
+	}
+
+	return games.Save(configPath)
+}
+
+// printStats prints the statistics of the vmf at path
+// and returns the exit code for the process
+func printStats(path string, asJSON bool) int {
+	stats, err := vmf.LoadStats(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to load", path+":", err)
+		return 1
+	}
+
+	if !asJSON {
+		fmt.Print(stats)
+		return 0
+	}
+
+	out, err := stats.JSON()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Println(string(out))
+	return 0
+}